@@ -0,0 +1,69 @@
+package pgproto
+
+import (
+	"strings"
+
+	pgquery "github.com/pganalyze/pg_query_go/v6"
+)
+
+// aggregateFuncs lists the names of the built-in aggregate functions. Without access to the catalog this is how we
+// recognize a plain function call as an aggregate, e.g: "sum(x)".
+var aggregateFuncs = map[string]bool{
+	"array_agg": true, "avg": true, "bit_and": true, "bit_or": true, "bit_xor": true, "bool_and": true,
+	"bool_or": true, "count": true, "every": true, "json_agg": true, "json_object_agg": true, "jsonb_agg": true,
+	"jsonb_object_agg": true, "max": true, "min": true, "range_agg": true, "range_intersect_agg": true,
+	"string_agg": true, "sum": true, "xmlagg": true, "corr": true, "covar_pop": true, "covar_samp": true,
+	"regr_avgx": true, "regr_avgy": true, "regr_count": true, "regr_intercept": true, "regr_r2": true,
+	"regr_slope": true, "regr_sxx": true, "regr_sxy": true, "regr_syy": true, "stddev": true, "stddev_pop": true,
+	"stddev_samp": true, "variance": true, "var_pop": true, "var_samp": true, "mode": true, "percentile_cont": true,
+	"percentile_disc": true, "rank": true, "dense_rank": true, "percent_rank": true, "cume_dist": true,
+}
+
+// nonNullAggregateFuncs lists the aggregate functions that never return NULL, other aggregates return NULL when
+// there are no (non-null) input rows.
+var nonNullAggregateFuncs = map[string]bool{"count": true, "regr_count": true}
+
+// castedFuncCall returns the function call that is type casted, looking through nested casts. It returns nil if the
+// cast is not applied to a function call.
+func castedFuncCall(cast *pgquery.TypeCast) *pgquery.FuncCall {
+	arg := cast.GetArg()
+	for arg.GetTypeCast() != nil {
+		arg = arg.GetTypeCast().GetArg()
+	}
+
+	return arg.GetFuncCall()
+}
+
+// isAggregate returns whether the function call is an aggregate, e.g: "count(*)" or the ordered-set aggregate
+// "percentile_cont(0.5) WITHIN GROUP (ORDER BY x)". Window function calls ("... OVER (...)") are not aggregates.
+func isAggregate(call *pgquery.FuncCall) bool {
+	switch {
+	case call == nil, call.GetOver() != nil:
+		return false
+	case call.GetAggWithinGroup(), call.GetAggStar(), call.GetAggDistinct(), call.GetAggFilter() != nil:
+		return true
+	}
+
+	return aggregateFuncs[funcName(call)]
+}
+
+// isNullableAggregate returns whether the aggregate function call may return NULL. E.g: "bool_and(x)" is NULL when
+// there are no rows to aggregate, while "count(x)" is 0.
+func isNullableAggregate(call *pgquery.FuncCall) bool {
+	return isAggregate(call) && !nonNullAggregateFuncs[funcName(call)]
+}
+
+// funcName returns the lower-cased name of a built-in function, it returns an empty string for functions in schemas
+// other than "pg_catalog".
+func funcName(call *pgquery.FuncCall) string {
+	names := call.GetFuncname()
+	if len(names) == 0 || len(names) > 2 {
+		return ""
+	}
+
+	if len(names) == 2 && names[0].GetString_().GetSval() != "pg_catalog" {
+		return ""
+	}
+
+	return strings.ToLower(names[len(names)-1].GetString_().GetSval())
+}
@@ -0,0 +1,111 @@
+package pgproto
+
+import "slices"
+
+// CloneAction returns a deep copy of the action, such that it can be changed without changing the original.
+func CloneAction(action Action) Action {
+	switch act := action.(type) {
+	case *SelectAction:
+		clone := *act
+		clone.ActionInfo = act.ActionInfo.clone()
+		clone.Inputs, clone.Outputs = cloneInputs(act.Inputs), cloneOutputs(act.Outputs)
+
+		return &clone
+	case *InsertAction:
+		clone := *act
+		clone.ActionInfo = act.ActionInfo.clone()
+		clone.Inputs, clone.Outputs = cloneInputs(act.Inputs), cloneOutputs(act.Outputs)
+
+		if act.OnConflict != nil {
+			onConflict := *act.OnConflict
+			onConflict.Columns = slices.Clone(onConflict.Columns)
+			onConflict.SetColumns = slices.Clone(onConflict.SetColumns)
+			clone.OnConflict = &onConflict
+		}
+
+		return &clone
+	case *UpdateAction:
+		clone := *act
+		clone.ActionInfo = act.ActionInfo.clone()
+		clone.Inputs, clone.Outputs = cloneInputs(act.Inputs), cloneOutputs(act.Outputs)
+		clone.DefaultedColumns = slices.Clone(act.DefaultedColumns)
+
+		return &clone
+	case *DeleteAction:
+		clone := *act
+		clone.ActionInfo = act.ActionInfo.clone()
+		clone.Inputs, clone.Outputs = cloneInputs(act.Inputs), cloneOutputs(act.Outputs)
+
+		return &clone
+	case *MergeAction:
+		clone := *act
+		clone.ActionInfo = act.ActionInfo.clone()
+		clone.Inputs, clone.Outputs = cloneInputs(act.Inputs), cloneOutputs(act.Outputs)
+
+		return &clone
+	default:
+		panicf(nil, "unsupported action: %T", action)
+
+		return nil
+	}
+}
+
+func (i ActionInfo) clone() ActionInfo {
+	i.Relations = slices.Clone(i.Relations)
+	for idx, rel := range i.Relations {
+		i.Relations[idx].Schema = clonePtr(rel.Schema)
+		i.Relations[idx].Aliases = slices.Clone(rel.Aliases)
+	}
+
+	i.Warnings = slices.Clone(i.Warnings)
+
+	return i
+}
+
+func cloneOutputs(outputs []*Output) []*Output {
+	if outputs == nil {
+		return nil
+	}
+
+	clones := make([]*Output, 0, len(outputs))
+	for _, output := range outputs {
+		clone := *output
+		clone.Type = output.Type.clone()
+		clones = append(clones, &clone)
+	}
+
+	return clones
+}
+
+func cloneInputs(inputs []*Input) []*Input {
+	if inputs == nil {
+		return nil
+	}
+
+	clones := make([]*Input, 0, len(inputs))
+	for _, input := range inputs {
+		clone := *input
+		clone.Type = input.Type.clone()
+		clone.locations = slices.Clone(input.locations)
+		clones = append(clones, &clone)
+	}
+
+	return clones
+}
+
+func (t TypeRef) clone() TypeRef {
+	t.Schema = clonePtr(t.Schema)
+	t.TypeMods = slices.Clone(t.TypeMods)
+
+	return t
+}
+
+func clonePtr[T any](ptr *T) *T {
+	if ptr == nil {
+		return nil
+	}
+
+	v := *ptr
+
+	return &v
+}
@@ -0,0 +1,27 @@
+package pgproto_test
+
+import (
+	"testing"
+
+	"github.com/crewlinker/pgproto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCloneAction(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT id::pg_catalog.int4 AS id_1 FROM public.foo`))
+	require.NoError(t, err)
+
+	orig := actions[0].(*pgproto.SelectAction)
+	clone := pgproto.CloneAction(orig).(*pgproto.SelectAction)
+	require.Equal(t, orig, clone)
+
+	clone.Outputs[0].Name = "other_1"
+	*clone.Outputs[0].Type.Schema = "other"
+	*clone.Relations[0].Schema = "other"
+	clone.Outputs = append(clone.Outputs, &pgproto.Output{Name: "extra_2"})
+
+	require.Len(t, orig.Outputs, 1)
+	require.Equal(t, "id_1", orig.Outputs[0].Name)
+	require.Equal(t, "pg_catalog", *orig.Outputs[0].Type.Schema)
+	require.Equal(t, "public", *orig.Relations[0].Schema)
+}
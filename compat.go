@@ -0,0 +1,137 @@
+package pgproto
+
+import (
+	"cmp"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// wireTypes groups protobuf types that are encoded the same on the wire, changing between types of the same group
+// is compatible.
+var wireTypes = map[string]string{
+	"bool":   "varint",
+	"int32":  "varint",
+	"int64":  "varint",
+	"string": "length-delimited",
+	"bytes":  "length-delimited",
+}
+
+// wireField describes a field of a generated message for the purpose of checking wire compatibility.
+type wireField struct {
+	name     string
+	typ      string
+	repeated bool
+}
+
+// WireCompatible determines if the messages generated for the next actions can be decoded by clients generated for
+// the previous actions, and vice versa. Actions are matched by their [MethodNames]. It returns false, with a reason for
+// each problem, when a method is removed, when a field number is reused for a field of a different type or when a
+// field is removed without reserving its number (since it may then be reused later). The options are those both
+// generations are generated with: they determine the protobuf types (e.g: [ProtoOptions.BigintAsString]) and the
+// numbers that are reserved in each response message of the next generation.
+func WireCompatible(prev, next []Action, opts ProtoOptions) (bool, []string) {
+	nextByName := map[string]Action{}
+	for idx, name := range MethodNames(next) {
+		nextByName[name] = next[idx]
+	}
+
+	var reasons []string
+
+	for idx, name := range MethodNames(prev) {
+		nextAction, ok := nextByName[name]
+		if !ok {
+			reasons = append(reasons, fmt.Sprintf("%s: method was removed", name))
+
+			continue
+		}
+
+		reasons = append(reasons, wireFieldsCompatible(name+": input",
+			inputWireFields(prev[idx].getInputs(), opts), inputWireFields(nextAction.getInputs(), opts), nil)...)
+		reasons = append(reasons, wireFieldsCompatible(name+": output",
+			outputWireFields(prev[idx].getOutputs(), opts), outputWireFields(nextAction.getOutputs(), opts),
+			reservedNumbers(nextAction.getOutputs(), opts.ReservedNumbers[name], opts.DeprecatedNumbers[name]))...)
+	}
+
+	return len(reasons) == 0, reasons
+}
+
+func wireFieldsCompatible(prefix string, prev, next map[int]wireField, reserved []int) (reasons []string) {
+	for _, num := range slices.Sorted(maps.Keys(prev)) {
+		oldField, newField := prev[num], next[num]
+
+		switch {
+		case newField.typ == "" && slices.Contains(reserved, num):
+			continue
+		case newField.typ == "":
+			reasons = append(reasons, fmt.Sprintf("%s %d (%s) was removed", prefix, num, oldField.name))
+		case oldField.repeated != newField.repeated:
+			reasons = append(reasons, fmt.Sprintf("%s %d (%s) changed between repeated and singular",
+				prefix, num, oldField.name))
+		case oldField.typ != newField.typ &&
+			(wireTypes[oldField.typ] == "" || wireTypes[oldField.typ] != wireTypes[newField.typ]):
+			reasons = append(reasons, fmt.Sprintf("%s %d (%s) changed type from %s to %s (%s)",
+				prefix, num, oldField.name, oldField.typ, newField.typ, newField.name))
+		}
+	}
+
+	return reasons
+}
+
+func outputWireFields(outputs []*Output, opts ProtoOptions) map[int]wireField {
+	fields := map[int]wireField{}
+	for _, output := range outputs {
+		fields[output.Number] = wireField{output.Name, wireTypeName(output.Type, opts), output.IsArray}
+	}
+
+	return fields
+}
+
+func inputWireFields(inputs []*Input, opts ProtoOptions) map[int]wireField {
+	fields := map[int]wireField{}
+	for _, input := range inputs {
+		fields[input.Number] = wireField{input.Name, wireTypeName(input.Type, opts), input.IsArray}
+	}
+
+	return fields
+}
+
+// wireTypeName returns the protobuf type of a Postgres type, or the Postgres type itself if it can't be mapped.
+func wireTypeName(typ TypeRef, opts ProtoOptions) string {
+	protoType, err := ProtoType(typ, opts)
+	if err != nil {
+		return strings.ToLower(typ.String())
+	}
+
+	return protoType
+}
+
+// outputShape describes an output for the purpose of comparing the shape of the messages generated for actions.
+type outputShape struct {
+	number    int
+	baseName  string
+	typ       string
+	arrayDims int
+	nullable  bool
+}
+
+// ShapeEqual returns whether the outputs of both actions have the same shape: the same numbers, base names, types,
+// array dimensions and nullability. The kind of action and the statement it was parsed from are ignored, so actions
+// with an equal shape can share a response message.
+func ShapeEqual(a, b Action) bool {
+	return slices.Equal(outputShapes(a.getOutputs()), outputShapes(b.getOutputs()))
+}
+
+func outputShapes(outputs []*Output) []outputShape {
+	shapes := make([]outputShape, 0, len(outputs))
+	for _, output := range outputs {
+		shapes = append(shapes, outputShape{
+			output.Number, output.BaseName, output.Type.String(), output.ArrayDims, output.Nullable,
+		})
+	}
+
+	slices.SortFunc(shapes, func(a, b outputShape) int { return cmp.Compare(a.number, b.number) })
+
+	return shapes
+}
@@ -0,0 +1,94 @@
+package pgproto_test
+
+import (
+	"testing"
+
+	"github.com/crewlinker/pgproto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWireCompatible(t *testing.T) {
+	parse := func(sql string) []pgproto.Action {
+		actions, err := pgproto.ParseFullTyped([]byte(sql))
+		require.NoError(t, err)
+
+		return actions
+	}
+
+	old := parse(`-- name: GetUser
+		SELECT id::uuid AS id_1, age::int4 AS age_2, nick::text AS nick_3 FROM users WHERE id = @id_1::uuid`)
+
+	t.Run("compatible", func(t *testing.T) {
+		ok, reasons := pgproto.WireCompatible(old, parse(`-- name: GetUser
+			SELECT id::uuid AS id_1, age::int8 AS age_2, nick::bytea AS nick_3, bio::text AS bio_4
+			FROM users WHERE id = @id_1::uuid`), pgproto.ProtoOptions{})
+		require.True(t, ok)
+		require.Empty(t, reasons)
+	})
+
+	t.Run("incompatible", func(t *testing.T) {
+		ok, reasons := pgproto.WireCompatible(old, parse(`-- name: GetUser
+			SELECT id::uuid AS id_1, age::text AS age_years_2 FROM users WHERE id = @id_1::int4`), pgproto.ProtoOptions{})
+		require.False(t, ok)
+		require.Equal(t, []string{
+			"GetUser: input 1 (id_1) changed type from string to int32 (id_1)",
+			"GetUser: output 2 (age_2) changed type from int32 to string (age_years_2)",
+			"GetUser: output 3 (nick_3) was removed",
+		}, reasons)
+	})
+
+	t.Run("removed with reservation", func(t *testing.T) {
+		next := parse(`-- name: GetUser
+			SELECT id::uuid AS id_1, age::int4 AS age_2 FROM users WHERE id = @id_1::uuid`)
+
+		ok, reasons := pgproto.WireCompatible(old, next,
+			pgproto.ProtoOptions{ReservedNumbers: map[string][]int{"GetUser": {3}}})
+		require.True(t, ok)
+		require.Empty(t, reasons)
+
+		ok, reasons = pgproto.WireCompatible(old, next,
+			pgproto.ProtoOptions{DeprecatedNumbers: map[string][]int{"GetUser": {3}}})
+		require.True(t, ok)
+		require.Empty(t, reasons)
+	})
+
+	t.Run("array input", func(t *testing.T) {
+		ok, reasons := pgproto.WireCompatible(old, parse(`-- name: GetUser
+			SELECT id::uuid AS id_1, age::int4 AS age_2, nick::text AS nick_3 FROM users WHERE id = ANY(@id_1::uuid[])`),
+			pgproto.ProtoOptions{})
+		require.False(t, ok)
+		require.Equal(t, []string{"GetUser: input 1 (id_1) changed between repeated and singular"}, reasons)
+	})
+
+	t.Run("bigint as string", func(t *testing.T) {
+		next := parse(`-- name: GetUser
+			SELECT id::uuid AS id_1, age::int8 AS age_2, nick::text AS nick_3 FROM users WHERE id = @id_1::uuid`)
+
+		ok, _ := pgproto.WireCompatible(old, next, pgproto.ProtoOptions{})
+		require.True(t, ok)
+
+		ok, reasons := pgproto.WireCompatible(old, next, pgproto.ProtoOptions{BigintAsString: true})
+		require.False(t, ok)
+		require.Equal(t, []string{"GetUser: output 2 (age_2) changed type from int32 to string (age_2)"}, reasons)
+	})
+
+	t.Run("removed method", func(t *testing.T) {
+		ok, reasons := pgproto.WireCompatible(old, parse(`-- name: ListUsers
+			SELECT id::uuid AS id_1 FROM users`), pgproto.ProtoOptions{})
+		require.False(t, ok)
+		require.Equal(t, []string{"GetUser: method was removed"}, reasons)
+	})
+}
+
+func TestShapeEqual(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`
+		SELECT id::uuid AS id_1, tags::text[] AS tags_2 FROM users WHERE id = @id_1::uuid;
+		DELETE FROM users RETURNING tags::text[] AS tags_2, uid::uuid AS id_1;
+		SELECT id::uuid AS id_1, tags::text AS tags_2 FROM users;
+		SELECT id::uuid AS user_id_1, tags::text[] AS tags_2 FROM users;`))
+	require.NoError(t, err)
+
+	require.True(t, pgproto.ShapeEqual(actions[0], actions[1]))
+	require.False(t, pgproto.ShapeEqual(actions[0], actions[2]))
+	require.False(t, pgproto.ShapeEqual(actions[0], actions[3]))
+}
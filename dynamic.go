@@ -0,0 +1,112 @@
+package pgproto
+
+import (
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+
+	// register the well-known types that fields may refer to.
+	_ "google.golang.org/protobuf/types/known/durationpb"
+	_ "google.golang.org/protobuf/types/known/structpb"
+	_ "google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// scalarFieldTypes maps protobuf scalar types onto their descriptor type.
+var scalarFieldTypes = map[string]descriptorpb.FieldDescriptorProto_Type{
+	"bool":   descriptorpb.FieldDescriptorProto_TYPE_BOOL,
+	"int32":  descriptorpb.FieldDescriptorProto_TYPE_INT32,
+	"int64":  descriptorpb.FieldDescriptorProto_TYPE_INT64,
+	"float":  descriptorpb.FieldDescriptorProto_TYPE_FLOAT,
+	"double": descriptorpb.FieldDescriptorProto_TYPE_DOUBLE,
+	"string": descriptorpb.FieldDescriptorProto_TYPE_STRING,
+	"bytes":  descriptorpb.FieldDescriptorProto_TYPE_BYTES,
+}
+
+// DynamicMessageDescriptor builds an in-memory descriptor of the response message for the action, as it would be
+// generated by [GenerateProto]. It can be used with dynamicpb.NewMessage to marshal rows without generated code.
+func DynamicMessageDescriptor(action Action) (protoreflect.MessageDescriptor, error) {
+	name := MethodName(action)
+	msg := &descriptorpb.DescriptorProto{Name: proto.String(name + "Response")}
+	file := &descriptorpb.FileDescriptorProto{
+		Name:        proto.String("pgproto/dynamic/" + snakeCase(name) + ".proto"),
+		Package:     proto.String("pgproto.dynamic"),
+		Syntax:      proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{msg},
+	}
+
+	if err := checkProtoFieldNames(action.getOutputs()); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	imports := map[string]bool{}
+	for _, output := range action.getOutputs() {
+		if _, err := output.ProtoField(ProtoOptions{}); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		protoType, _ := ProtoType(output.Type, ProtoOptions{})
+		field := &descriptorpb.FieldDescriptorProto{
+			Name:     proto.String(snakeCase(output.BaseName)),
+			JsonName: proto.String(output.JSONName()),
+			Number:   proto.Int32(int32(output.Number)), //nolint:gosec
+			Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+		}
+
+		if scalar, ok := scalarFieldTypes[protoType]; ok {
+			field.Type = scalar.Enum()
+		} else {
+			field.Type = descriptorpb.FieldDescriptorProto_TYPE_MESSAGE.Enum()
+			field.TypeName = proto.String("." + protoType)
+
+			if imp := wellKnownImports[protoType]; !imports[imp] {
+				imports[imp] = true
+				file.Dependency = append(file.Dependency, imp)
+			}
+		}
+
+		switch {
+		case output.IsArray:
+			field.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		case output.Nullable: // proto3 optional fields are declared in a synthetic oneof
+			field.Proto3Optional = proto.Bool(true)
+			field.OneofIndex = proto.Int32(int32(len(msg.GetOneofDecl()))) //nolint:gosec
+			msg.OneofDecl = append(msg.OneofDecl, &descriptorpb.OneofDescriptorProto{
+				Name: proto.String("_" + field.GetName()),
+			})
+		}
+
+		msg.Field = append(msg.Field, field)
+	}
+
+	desc, err := protodesc.NewFile(file, protoregistry.GlobalFiles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build descriptor: %w", err)
+	}
+
+	return desc.Messages().Get(0), nil
+}
+
+// jsonName returns the JSON name of a field like protoc derives it from the field name: "user_id" becomes "userId".
+func jsonName(fieldName string) string {
+	var bldr strings.Builder
+
+	upper := false
+	for _, r := range fieldName {
+		switch {
+		case r == '_':
+			upper = true
+		case upper:
+			bldr.WriteString(strings.ToUpper(string(r)))
+			upper = false
+		default:
+			bldr.WriteRune(r)
+		}
+	}
+
+	return bldr.String()
+}
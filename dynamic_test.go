@@ -0,0 +1,38 @@
+package pgproto_test
+
+import (
+	"testing"
+
+	"github.com/crewlinker/pgproto"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestDynamicMessageDescriptor(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`
+		SELECT id::uuid AS id_1, tags::text[] AS tags_3, created::timestamptz AS created_at_4 FROM users`))
+	require.NoError(t, err)
+
+	desc, err := pgproto.DynamicMessageDescriptor(actions[0])
+	require.NoError(t, err)
+	require.Equal(t, protoreflect.Name("SelectUsersResponse"), desc.Name())
+	require.Equal(t, 3, desc.Fields().Len())
+
+	id := desc.Fields().ByNumber(1)
+	require.Equal(t, protoreflect.Name("id"), id.Name())
+	require.Equal(t, protoreflect.StringKind, id.Kind())
+
+	tags := desc.Fields().ByNumber(3)
+	require.Equal(t, protoreflect.Name("tags"), tags.Name())
+	require.True(t, tags.IsList())
+
+	created := desc.Fields().ByName("created_at")
+	require.Equal(t, protoreflect.FieldNumber(4), created.Number())
+	require.Equal(t, "createdAt", created.JSONName())
+	require.Equal(t, protoreflect.FullName("google.protobuf.Timestamp"), created.Message().FullName())
+
+	msg := dynamicpb.NewMessage(desc)
+	msg.Set(id, protoreflect.ValueOfString("abc"))
+	require.Equal(t, "abc", msg.Get(id).String())
+}
@@ -0,0 +1,153 @@
+package pgproto
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	pgquery "github.com/pganalyze/pg_query_go/v6"
+)
+
+// ParseError describes why a statement could not be parsed, such that (editor) tooling can present it as a
+// diagnostic. The wrapped error can be tested against the sentinel errors, e.g: [ErrColumnWithoutCast].
+type ParseError struct {
+	// File is the path of the parsed file, if parsed with [ParseFile].
+	File string
+	// Statement is the (0-based) index of the statement in the input.
+	Statement int
+	// Offset is the byte offset of the most specific location of the error, e.g: the offending result target.
+	Offset int32
+	// Line and Column are the 1-based position of the Offset.
+	Line, Column int
+	// Alias of the offending output, if any.
+	Alias string
+	// SourceColumn is the column that the offending output is derived from, if any.
+	SourceColumn string
+	// Err is the wrapped error.
+	Err error
+}
+
+func (e *ParseError) Error() string {
+	if e.File != "" {
+		return e.File + ": " + e.Err.Error()
+	}
+
+	return e.Err.Error()
+}
+
+func (e *ParseError) Unwrap() error { return e.Err }
+
+// newParseError returns the error for the statement at the index, located by the most specific [PositionError].
+func newParseError(idx int, err error, input []byte) *ParseError {
+	resolvePositions(err, input)
+
+	perr := &ParseError{Statement: idx, Err: err}
+
+	var pos *PositionError
+	for target := err; errors.As(target, &pos); target = pos.Err {
+		perr.Offset, perr.Line, perr.Column = pos.Offset, pos.Line, pos.Column
+		perr.Alias, perr.SourceColumn = cmp.Or(pos.alias, perr.Alias), cmp.Or(pos.column, perr.SourceColumn)
+	}
+
+	return perr
+}
+
+// PositionError locates an error in the parsed SQL, e.g: the statement or result target that caused it.
+type PositionError struct {
+	// What is located, e.g: "statement" or "result_target".
+	What string
+	// Offset is the byte offset into the input.
+	Offset int32
+	// Line and Column are the 1-based position in the input, they are zero when unknown.
+	Line, Column int
+	// Err is the error at this position.
+	Err error
+
+	alias, column string // of the result target at this position
+}
+
+func (e *PositionError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s@%d: %v", e.What, e.Offset, e.Err)
+	}
+
+	return fmt.Sprintf("%s at %d:%d: %v", e.What, e.Line, e.Column, e.Err)
+}
+
+func (e *PositionError) Unwrap() error { return e.Err }
+
+// positionErrorf returns an error located at the offset in the input.
+func positionErrorf(what string, offset int32, format string, args ...any) error {
+	return &PositionError{What: what, Offset: offset, Err: formattedError{format, args}}
+}
+
+// resTargetErrorf returns an error located at the result target, which records its alias and source column.
+func resTargetErrorf(rtgt *pgquery.ResTarget, format string, args ...any) error {
+	return &PositionError{
+		What: "result_target", Offset: rtgt.GetLocation(), Err: formattedError{format, args},
+		alias: strings.TrimSuffix(rtgt.GetName(), nullableMarker), column: sourceColumn(rtgt.GetVal()),
+	}
+}
+
+// formattedError formats its message when it is needed rather than when it is created, such that the positions of
+// the errors it wraps can still be resolved after the error is created.
+type formattedError struct {
+	format string
+	args   []any
+}
+
+func (e formattedError) Error() string { return fmt.Errorf(e.format, e.args...).Error() }
+
+func (e formattedError) Unwrap() []error {
+	switch err := fmt.Errorf(e.format, e.args...).(type) { //nolint:errorlint
+	case interface{ Unwrap() []error }:
+		return err.Unwrap()
+	case interface{ Unwrap() error }:
+		if wrapped := err.Unwrap(); wrapped != nil {
+			return []error{wrapped}
+		}
+	}
+
+	return nil
+}
+
+// resolvePositions sets the line and column of every [PositionError] in the (joined) error tree.
+func resolvePositions(err error, input []byte) {
+	switch err := err.(type) { //nolint:errorlint
+	case *PositionError:
+		err.Line, err.Column = linePosition(input, err.Offset)
+		resolvePositions(err.Err, input)
+	case interface{ Unwrap() []error }:
+		for _, err := range err.Unwrap() {
+			resolvePositions(err, input)
+		}
+	case interface{ Unwrap() error }:
+		resolvePositions(err.Unwrap(), input)
+	}
+}
+
+// linePosition returns the 1-based line and column of the byte offset in the input. The column counts characters,
+// not bytes. Whitespace at the offset is skipped, since statements are located right after the preceding semicolon.
+func linePosition(input []byte, offset int32) (line, column int) {
+	if offset < 0 || int(offset) > len(input) {
+		return 0, 0
+	}
+
+	end := len(input) - len(bytes.TrimLeftFunc(input[offset:], unicode.IsSpace))
+
+	line, column = 1, 1
+	for _, r := range string(input[:end]) {
+		if r == '\n' {
+			line, column = line+1, 1
+
+			continue
+		}
+
+		column++
+	}
+
+	return line, column
+}
@@ -0,0 +1,162 @@
+package pgproto
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/format"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// ErrGoFieldCollision is returned when the names of different outputs map onto the same Go field name, e.g:
+// "userId_1" and "user_id_2" both become "UserId".
+var ErrGoFieldCollision = errors.New("go field name collision")
+
+// goImports maps Go types onto the package that needs to be imported to use them.
+var goImports = map[string]string{
+	"time.Time":       "time",
+	"time.Duration":   "time",
+	"json.RawMessage": "encoding/json",
+}
+
+// GoOptions configure the generation of Go code.
+type GoOptions struct {
+	// Package is the name of the Go package of the generated file.
+	Package string
+	// GeneratorName is mentioned in the "Code generated" banner of the file, it defaults to "pgproto".
+	GeneratorName string
+	// TypeMapper maps Postgres types onto Go types, it defaults to [GoType]. This allows Go types to be chosen
+	// independently of the protobuf types, e.g: "uuid" onto "uuid.UUID" instead of "string".
+	TypeMapper func(typ TypeRef) (string, error)
+	// Imports maps the Go types returned by the TypeMapper onto the package that needs to be imported to use them,
+	// e.g: "uuid.UUID" onto "github.com/google/uuid".
+	Imports map[string]string
+}
+
+// goType maps the Postgres type onto a Go type with the configured type mapper.
+func (opts GoOptions) goType(typ TypeRef) (string, error) {
+	if opts.TypeMapper == nil {
+		return GoType(typ)
+	}
+
+	return opts.TypeMapper(typ)
+}
+
+// goImport returns the package that needs to be imported to use the Go type, if any.
+func (opts GoOptions) goImport(goType string) (string, bool) {
+	if imp, ok := opts.Imports[goType]; ok {
+		return imp, true
+	}
+
+	imp, ok := goImports[goType]
+
+	return imp, ok
+}
+
+// GenerateGoStructs generates a Go file that declares a response struct for each action. The struct is
+// named after the action's [MethodNames] and has an exported field for each output. Each field is tagged with its
+// protojson name, such that the struct serializes to the same JSON as the message generated by [GenerateProto], and
+// with the output's number.
+func GenerateGoStructs(actions []Action, opts GoOptions) ([]byte, error) {
+	var body bytes.Buffer
+
+	imports := map[string]bool{}
+	for idx, name := range MethodNames(actions) {
+		if err := checkGoFieldNames(actions[idx].getOutputs()); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		fmt.Fprintf(&body, "\n// %sResponse is the response of %s.\ntype %sResponse struct {\n", name, name, name)
+
+		for _, output := range actions[idx].getOutputs() {
+			field, err := output.goField(opts)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+
+			goType, _ := opts.goType(output.Type)
+			if imp, ok := opts.goImport(goType); ok {
+				imports[imp] = true
+			}
+
+			writeComment(&body, "\t", output.Doc)
+			fmt.Fprintf(&body, "\t%s\n", field)
+		}
+
+		body.WriteString("}\n")
+	}
+
+	var buf bytes.Buffer
+	writeBanner(&buf, "//", opts.GeneratorName)
+	fmt.Fprintf(&buf, "\npackage %s\n", opts.Package)
+
+	if len(imports) > 0 {
+		buf.WriteString("\nimport (\n")
+
+		for _, imp := range slices.Sorted(maps.Keys(imports)) {
+			fmt.Fprintf(&buf, "\t%q\n", imp)
+		}
+
+		buf.WriteString(")\n")
+	}
+
+	buf.Write(body.Bytes())
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w", err)
+	}
+
+	return src, nil
+}
+
+// checkGoFieldNames checks that each output maps onto a distinct Go field name.
+func checkGoFieldNames(outputs []*Output) error {
+	fieldOf := map[string]*Output{}
+	for _, output := range outputs {
+		field := camelCase(output.BaseName)
+		if other, exists := fieldOf[field]; exists {
+			return fmt.Errorf("%w: outputs '%s' and '%s' both map onto '%s'",
+				ErrGoFieldCollision, other.Name, output.Name, field)
+		}
+
+		fieldOf[field] = output
+	}
+
+	return nil
+}
+
+// GoField returns the Go struct field declaration for the output, e.g: "UserId int32 `json:\"userId\" pgproto:\"1\"`".
+// The field is named by camel-casing the name of the output, initialisms such as "ID" are not recognized. Arrays are
+// declared as slices and nullable outputs as pointers, that are omitted from JSON when nil. The "pgproto" tag holds
+// the output's number, which identifies the field as long as it is not renumbered.
+func (o *Output) GoField() (string, error) {
+	return o.goField(GoOptions{})
+}
+
+// goField returns the Go struct field declaration for the output, with its type mapped by the options.
+func (o *Output) goField(opts GoOptions) (string, error) {
+	goType, err := opts.goType(o.Type)
+	if err != nil {
+		return "", fmt.Errorf("output '%s': %w", o.Name, err)
+	}
+
+	tag := o.JSONName()
+
+	switch {
+	case o.IsArray:
+		goType = strings.Repeat("[]", o.ArrayDims) + goType
+	case o.Nullable:
+		goType = "*" + goType
+		tag += ",omitempty"
+	}
+
+	return fmt.Sprintf("%s %s `json:%q pgproto:\"%d\"`", camelCase(o.BaseName), goType, tag, o.Number), nil
+}
+
+// JSONName returns the name of the output's field in the protojson encoding of the response, e.g: "userId".
+func (o *Output) JSONName() string {
+	return jsonName(snakeCase(o.BaseName))
+}
@@ -0,0 +1,126 @@
+package pgproto_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/crewlinker/pgproto"
+	"github.com/stretchr/testify/require"
+)
+
+var nickDomain = pgproto.WithDomains(map[string]pgproto.DomainInfo{"nick": {Type: pgproto.TypeRef{Name: "text"}}})
+
+func TestGenerateGoStructsGolden(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "go_struct_select.sql"))
+	require.NoError(t, err)
+
+	actions, err := pgproto.ParseFullTyped(data, nickDomain)
+	require.NoError(t, err)
+
+	src, err := pgproto.GenerateGoStructs(actions, pgproto.GoOptions{Package: "usersv1"})
+	require.NoError(t, err)
+
+	golden(t, "go_struct_select.sql.go", src)
+}
+
+func TestGenerateGoStructsJSONTags(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "go_struct_select.sql"))
+	require.NoError(t, err)
+
+	actions, err := pgproto.ParseFullTyped(data, nickDomain)
+	require.NoError(t, err)
+
+	src, err := pgproto.GenerateGoStructs(actions, pgproto.GoOptions{Package: "usersv1"})
+	require.NoError(t, err)
+
+	file, err := parser.ParseFile(token.NewFileSet(), "", src, 0)
+	require.NoError(t, err)
+
+	tags := map[string]string{}
+	ast.Inspect(file, func(node ast.Node) bool {
+		if field, ok := node.(*ast.Field); ok && field.Tag != nil {
+			tag, err := strconv.Unquote(field.Tag.Value)
+			require.NoError(t, err)
+
+			tags[field.Names[0].Name] = reflect.StructTag(tag).Get("json")
+		}
+
+		return true
+	})
+
+	require.Equal(t, map[string]string{
+		"UserId":    "userId",
+		"NickName":  "nickName,omitempty",
+		"CreatedAt": "createdAt",
+		"Tags":      "tags",
+	}, tags)
+
+	desc, err := pgproto.DynamicMessageDescriptor(actions[0])
+	require.NoError(t, err)
+
+	for i := range desc.Fields().Len() {
+		field := desc.Fields().Get(i)
+		require.Equal(t, actions[0].(*pgproto.SelectAction).Outputs[i].JSONName(), field.JSONName())
+	}
+}
+
+func TestGeneratedBanner(t *testing.T) {
+	generated := regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT id::int4 AS id_1 FROM users`))
+	require.NoError(t, err)
+
+	protoSrc, err := pgproto.GenerateProto(actions, pgproto.ProtoOptions{})
+	require.NoError(t, err)
+	require.Regexp(t, generated, strings.SplitN(string(protoSrc), "\n", 2)[0])
+	require.True(t, strings.HasPrefix(string(protoSrc), "// Code generated by pgproto; DO NOT EDIT.\n"))
+
+	goSrc, err := pgproto.GenerateGoStructs(actions, pgproto.GoOptions{Package: "users", GeneratorName: "usergen"})
+	require.NoError(t, err)
+	require.Regexp(t, generated, strings.SplitN(string(goSrc), "\n", 2)[0])
+	require.True(t, strings.HasPrefix(string(goSrc), "// Code generated by usergen; DO NOT EDIT.\n"))
+}
+
+func TestGenerateGoStructsTypeMapper(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1, age::int4 AS age_3 FROM users`))
+	require.NoError(t, err)
+
+	src, err := pgproto.GenerateGoStructs(actions, pgproto.GoOptions{
+		Package: "users",
+		TypeMapper: func(typ pgproto.TypeRef) (string, error) {
+			if typ.Name == "uuid" {
+				return "uuid.UUID", nil
+			}
+
+			return pgproto.GoType(typ)
+		},
+		Imports: map[string]string{"uuid.UUID": "github.com/google/uuid"},
+	})
+	require.NoError(t, err)
+	require.Contains(t, string(src), "\t\"github.com/google/uuid\"\n")
+	require.Contains(t, string(src), "Id  uuid.UUID `json:\"id\" pgproto:\"1\"`")
+	require.Contains(t, string(src), "Age int32     `json:\"age\" pgproto:\"3\"`")
+
+	protoSrc, err := pgproto.GenerateProto(actions, pgproto.ProtoOptions{})
+	require.NoError(t, err)
+	require.Contains(t, string(protoSrc), "string id = 1;")
+}
+
+func TestGenerateGoStructsFieldCollision(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT a::int4 AS "userId_1", b::int4 AS user_id_2 FROM users`))
+	require.NoError(t, err)
+
+	_, err = pgproto.GenerateGoStructs(actions, pgproto.GoOptions{Package: "users"})
+	require.ErrorIs(t, err, pgproto.ErrGoFieldCollision)
+	require.ErrorContains(t, err, "outputs 'userId_1' and 'user_id_2' both map onto 'UserId'")
+
+	_, err = pgproto.GeneratePgxScanners(actions, pgproto.GoOptions{Package: "users"})
+	require.ErrorIs(t, err, pgproto.ErrGoFieldCollision)
+}
@@ -0,0 +1,288 @@
+package pgproto
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	pgquery "github.com/pganalyze/pg_query_go/v6"
+	"google.golang.org/protobuf/proto"
+)
+
+// Input describes a named argument of an action, e.g: "@tenant_1::uuid". Positional arguments (e.g: "$1::uuid") are
+// numbered by their position and have no name.
+type Input struct {
+	Number   int
+	Name     string
+	BaseName string
+	Type     TypeRef
+	// IsArray is set when the argument is cast to an array type, e.g: "@ids_1::uuid[]". ArrayDims holds the number of
+	// dimensions of the array.
+	IsArray   bool
+	ArrayDims int
+
+	locations []int32 // of the "@" of each use of a named argument
+}
+
+// typeString returns the type of the input as it would be written in SQL, e.g: "uuid[]".
+func (i *Input) typeString() string {
+	return i.Type.String() + strings.Repeat("[]", i.ArrayDims)
+}
+
+// ErrInputWithoutCast is returned when a named argument is not type casted.
+var ErrInputWithoutCast = errors.New(`no type cast for named argument, use "::" to declare the type`)
+
+// ErrInputTypeConflict is returned when the same named argument is cast to different types.
+var ErrInputTypeConflict = errors.New("named argument is cast to different types")
+
+// ErrMixedInputStyles is returned when a statement uses both positional (e.g: "$1") and named arguments (e.g:
+// "@id_1"), since both are numbered and their numbers would collide.
+var ErrMixedInputStyles = errors.New("positional and named arguments can't be mixed in a statement")
+
+// isParamOperator returns whether the expression applies the "@" prefix operator.
+func isParamOperator(expr *pgquery.A_Expr) bool {
+	return expr != nil && expr.GetLexpr() == nil && len(expr.GetName()) == 1 && svalString(expr.GetName()[0]) == "@"
+}
+
+// paramName returns the name of the named argument if the expression is one. Named arguments are written as "@name"
+// which Postgres parses as the prefix operator "@" applied to a column reference. Other uses of the operator, such as
+// the absolute value of a number in "@ -5", are not named arguments.
+func paramName(expr *pgquery.A_Expr) (string, bool) {
+	if !isParamOperator(expr) {
+		return "", false
+	}
+
+	return paramColumnName(expr.GetRexpr())
+}
+
+// paramColumnName returns the name of the column reference that follows the "@" of a named argument.
+func paramColumnName(node *pgquery.Node) (string, bool) {
+	fields := node.GetColumnRef().GetFields()
+	if len(fields) != 1 || fields[0].GetString_() == nil {
+		return "", false
+	}
+
+	return svalString(fields[0]), true
+}
+
+// parsePositionalInput parses a single positional argument given the type it is cast to.
+func parsePositionalInput(
+	param *pgquery.ParamRef, typeName *pgquery.TypeName, opts Options,
+) (input *Input, err error) {
+	input = &Input{Number: int(param.GetNumber())}
+
+	input.Type, err = parseTypeName(typeName)
+	if err != nil {
+		return nil, inputErrorf(param.GetLocation(), "argument $%d: %w", input.Number, err)
+	}
+
+	input.Type = canonicalType(input.Type, opts)
+	input.ArrayDims = len(typeName.GetArrayBounds())
+	input.IsArray = input.ArrayDims > 0
+
+	return input, nil
+}
+
+// parseInput parses a single named argument given its name and the type it is cast to.
+func parseInput(name string, typeName *pgquery.TypeName, location int32, opts Options) (input *Input, err error) {
+	input = &Input{Name: name}
+
+	input.BaseName, input.Number, err = numberedName(name, opts.SuffixPattern)
+	if err != nil {
+		return nil, inputErrorf(location, "%w", err)
+	}
+
+	if !validBaseName(input.BaseName) {
+		return nil, inputErrorf(location, "argument '%s': %w", name, ErrInvalidBaseName)
+	}
+
+	input.Type, err = parseTypeName(typeName)
+	if err != nil {
+		return nil, inputErrorf(location, "argument '%s': %w", name, err)
+	}
+
+	input.Type = canonicalType(input.Type, opts)
+	input.ArrayDims = len(typeName.GetArrayBounds())
+	input.IsArray = input.ArrayDims > 0
+
+	return input, nil
+}
+
+// parseInputs walks the statement to find its named arguments, and positional arguments if enabled. Positional
+// arguments are rejected in statements with named arguments either way, since the named arguments are rewritten to
+// positional ones that would clash with them. Since the "::" cast binds tighter than the "@" operator, "@x::text"
+// results in a cast of the column reference ("@ (x::text)"), while "CAST(@x AS text)" results in a cast of the operator
+// expression. Both forms are supported. Arguments may be used multiple times, as long as they are cast to the same
+// type each time.
+func parseInputs(stmt proto.Message, opts Options) (inputs []*Input, err error) {
+	byNumber := map[int]*Input{}
+	add := func(input *Input, location, at int32) error {
+		existing, exists := byNumber[input.Number]
+		switch {
+		case len(inputs) > 0 && (inputs[0].Name == "") != (input.Name == ""):
+			return inputErrorf(location, "argument '%s': %w", cmp.Or(input.Name, "$"+strconv.Itoa(input.Number)),
+				ErrMixedInputStyles)
+		case !exists:
+			byNumber[input.Number] = input
+			inputs = append(inputs, input)
+		case existing.Name != input.Name:
+			return inputErrorf(location, "%w, %d is already used by: %s",
+				ErrDuplicateNumberSuffix, input.Number, existing.Name)
+		case existing.typeString() != input.typeString():
+			return inputErrorf(location, "argument '%s': %w: %s and %s",
+				input.Name, ErrInputTypeConflict, existing.typeString(), input.typeString())
+		}
+
+		if at >= 0 {
+			byNumber[input.Number].locations = append(byNumber[input.Number].locations, at)
+		}
+
+		return nil
+	}
+
+	var inferred map[*pgquery.A_Expr]*pgquery.TypeName
+	if opts.InputTypeInference {
+		inferred = inferInputTypes(stmt)
+	}
+
+	var positional *pgquery.ParamRef // the first positional argument, when not parsed as input
+	walk(stmt, func(msg proto.Message) bool {
+		var (
+			name         string
+			typeName     *pgquery.TypeName
+			location, at int32
+		)
+
+		switch node := msg.(type) {
+		case *pgquery.ParamRef: // e.g: $1 (when not cast)
+			if opts.PositionalInputNumbering {
+				err = errors.Join(err, inputErrorf(node.GetLocation(), "argument $%d: %w",
+					node.GetNumber(), ErrInputWithoutCast))
+			} else if positional == nil {
+				positional = node
+			}
+
+			return true
+		case *pgquery.TypeCast: // e.g: CAST(@x AS text)
+			if param := node.GetArg().GetParamRef(); param != nil && opts.PositionalInputNumbering {
+				input, perr := parsePositionalInput(param, node.GetTypeName(), opts)
+				if perr == nil {
+					perr = add(input, param.GetLocation(), -1)
+				}
+
+				err = errors.Join(err, perr)
+
+				return false
+			}
+
+			var ok bool
+			if name, ok = paramName(node.GetArg().GetAExpr()); !ok {
+				return true
+			}
+
+			typeName, location, at = node.GetTypeName(), node.GetLocation(), node.GetArg().GetAExpr().GetLocation()
+		case *pgquery.A_Expr: // e.g: @x::text
+			var ok bool
+			if name, ok = paramName(node); ok {
+				if typeName, ok = inferred[node]; !ok {
+					err = errors.Join(err, inputErrorf(node.GetLocation(), "argument '%s': %w", name, ErrInputWithoutCast))
+
+					return false
+				}
+
+				location, at = node.GetLocation(), node.GetLocation()
+
+				break
+			}
+
+			cast := node.GetRexpr().GetTypeCast()
+			if !isParamOperator(node) || cast == nil {
+				return true
+			}
+
+			if name, ok = paramColumnName(cast.GetArg()); !ok {
+				return true
+			}
+
+			typeName, location, at = cast.GetTypeName(), node.GetLocation(), node.GetLocation()
+		default:
+			return true
+		}
+
+		input, perr := parseInput(name, typeName, location, opts)
+		if perr == nil {
+			perr = add(input, location, at)
+		}
+
+		err = errors.Join(err, perr)
+
+		return false
+	})
+
+	if positional != nil && len(inputs) > 0 {
+		err = errors.Join(err, inputErrorf(positional.GetLocation(), "argument $%d: %w",
+			positional.GetNumber(), ErrMixedInputStyles))
+	}
+
+	return inputs, err
+}
+
+// inferInputTypes returns the types of named arguments without a type cast that are compared to a type casted
+// expression, e.g: "@tenant_1" in "tenant_id::uuid = @tenant_1" has type "uuid". It is keyed by the "@" expression.
+func inferInputTypes(stmt proto.Message) map[*pgquery.A_Expr]*pgquery.TypeName {
+	inferred := map[*pgquery.A_Expr]*pgquery.TypeName{}
+	walk(stmt, func(msg proto.Message) bool {
+		expr, ok := msg.(*pgquery.A_Expr)
+		if !ok || expr.GetKind() != pgquery.A_Expr_Kind_AEXPR_OP || expr.GetLexpr() == nil {
+			return true
+		}
+
+		for _, sides := range [][2]*pgquery.Node{{expr.GetLexpr(), expr.GetRexpr()}, {expr.GetRexpr(), expr.GetLexpr()}} {
+			param, other := sides[0].GetAExpr(), sides[1].GetTypeCast()
+			if _, isParam := paramName(param); isParam && other != nil {
+				inferred[param] = other.GetTypeName()
+			}
+		}
+
+		return true
+	})
+
+	return inferred
+}
+
+func inputErrorf(location int32, format string, args ...any) error {
+	return positionErrorf("input", location, format, args...)
+}
+
+// CollectInputs returns the union of the inputs of all actions, ordered by number. This supports a single request
+// message that feeds several statements. Inputs with the same number must have the same name and type.
+func CollectInputs(actions []Action) ([]Input, error) {
+	byNumber := map[int]Input{}
+	for idx, name := range MethodNames(actions) {
+		for _, input := range actions[idx].getInputs() {
+			existing, exists := byNumber[input.Number]
+
+			switch {
+			case !exists:
+				byNumber[input.Number] = *input
+			case existing.Name != input.Name:
+				return nil, fmt.Errorf("%s: %w, %d is already used by: %s",
+					name, ErrDuplicateNumberSuffix, input.Number, existing.Name)
+			case existing.typeString() != input.typeString():
+				return nil, fmt.Errorf("%s: argument '%s': %w: %s and %s",
+					name, input.Name, ErrInputTypeConflict, existing.typeString(), input.typeString())
+			}
+		}
+	}
+
+	inputs := make([]Input, 0, len(byNumber))
+	for _, num := range slices.Sorted(maps.Keys(byNumber)) {
+		inputs = append(inputs, byNumber[num])
+	}
+
+	return inputs, nil
+}
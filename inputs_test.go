@@ -0,0 +1,119 @@
+package pgproto_test
+
+import (
+	"testing"
+
+	"github.com/crewlinker/pgproto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCollectInputs(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`
+		SELECT id::uuid AS id_1 FROM users WHERE tenant = @tenant_1::uuid;
+		DELETE FROM sessions WHERE tenant = @tenant_1::uuid AND created < @before_2::timestamptz;
+	`))
+	require.NoError(t, err)
+
+	inputs, err := pgproto.CollectInputs(actions)
+	require.NoError(t, err)
+	require.Len(t, inputs, 2)
+	require.Equal(t, "tenant_1", inputs[0].Name)
+	require.Equal(t, "uuid", inputs[0].Type.Name)
+	require.Equal(t, "before_2", inputs[1].Name)
+}
+
+func TestCollectInputsConflict(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`
+		SELECT id::uuid AS id_1 FROM users WHERE tenant = @tenant_1::uuid;
+		DELETE FROM sessions WHERE tenant = @tenant_1::text;
+	`))
+	require.NoError(t, err)
+
+	_, err = pgproto.CollectInputs(actions)
+	require.ErrorIs(t, err, pgproto.ErrInputTypeConflict)
+
+	actions, err = pgproto.ParseFullTyped([]byte(`
+		SELECT id::uuid AS id_1 FROM users WHERE tenant = @tenant_1::uuid;
+		DELETE FROM sessions WHERE id = @id_1::uuid;
+	`))
+	require.NoError(t, err)
+
+	_, err = pgproto.CollectInputs(actions)
+	require.ErrorIs(t, err, pgproto.ErrDuplicateNumberSuffix)
+}
+
+func TestNamedInputs(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`
+		SELECT id::uuid AS id_1 FROM users WHERE tenant = @tenant_1::uuid;
+		SELECT id::uuid AS id_1 FROM users WHERE tenant = CAST(@tenant_1 AS uuid) AND name = @name_2::text;`))
+	require.NoError(t, err)
+
+	for _, action := range actions {
+		inputs := action.(*pgproto.SelectAction).Inputs
+		require.Equal(t, 1, inputs[0].Number)
+		require.Equal(t, "tenant_1", inputs[0].Name)
+		require.Equal(t, "tenant", inputs[0].BaseName)
+		require.Equal(t, "uuid", inputs[0].Type.Name)
+	}
+
+	require.Len(t, actions[1].(*pgproto.SelectAction).Inputs, 2)
+	require.Equal(t, "text", actions[1].(*pgproto.SelectAction).Inputs[1].Type.Name)
+}
+
+func TestNamedInputWithoutNumberSuffix(t *testing.T) {
+	_, err := pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1 FROM users WHERE id = @user_id::uuid`))
+	require.ErrorIs(t, err, pgproto.ErrNamedWithoutNumberSuffix)
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1 FROM users WHERE id = @id_1::uuid AND x = @x_1::int4`))
+	require.ErrorIs(t, err, pgproto.ErrDuplicateNumberSuffix)
+}
+
+func TestInputTypeInference(t *testing.T) {
+	sql := []byte(`SELECT id::uuid AS id_1 FROM users
+		WHERE tenant_id::uuid = @tenant_1 AND @age_2 > age::int4 AND name = @name_3::text`)
+
+	_, err := pgproto.ParseFullTyped(sql)
+	require.ErrorIs(t, err, pgproto.ErrInputWithoutCast)
+
+	actions, err := pgproto.ParseFullTyped(sql, pgproto.WithInputTypeInference())
+	require.NoError(t, err)
+
+	inputs := actions[0].(*pgproto.SelectAction).Inputs
+	require.Len(t, inputs, 3)
+	require.Equal(t, "tenant_1", inputs[0].Name)
+	require.Equal(t, "uuid", inputs[0].Type.Name)
+	require.Equal(t, "age_2", inputs[1].Name)
+	require.Equal(t, "int4", inputs[1].Type.Name)
+	require.Equal(t, "text", inputs[2].Type.Name)
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1 FROM users WHERE tenant_id = @tenant_1`),
+		pgproto.WithInputTypeInference())
+	require.ErrorIs(t, err, pgproto.ErrInputWithoutCast)
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1 FROM users
+		WHERE tenant_id::uuid = @tenant_1 AND owner_id = @tenant_1::text`), pgproto.WithInputTypeInference())
+	require.ErrorIs(t, err, pgproto.ErrInputTypeConflict)
+}
+
+func TestArrayInputs(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1 FROM users WHERE id = ANY(@ids_1::uuid[])`))
+	require.NoError(t, err)
+
+	input := actions[0].(*pgproto.SelectAction).Inputs[0]
+	require.True(t, input.IsArray)
+	require.Equal(t, 1, input.ArrayDims)
+	require.Equal(t, "uuid", input.Type.Name)
+
+	out, err := pgproto.GenerateProto(actions, pgproto.ProtoOptions{ServiceName: "Users"})
+	require.NoError(t, err)
+	require.Contains(t, string(out), "message SelectUsersRequest {\n  repeated string ids = 1;\n}")
+
+	prepares, err := pgproto.GeneratePrepares(actions, pgproto.PrepareOptions{})
+	require.NoError(t, err)
+	require.Contains(t, string(prepares), `PREPARE "SelectUsers"(uuid[]) AS`)
+
+	_, err = pgproto.ParseFullTyped([]byte(
+		`SELECT id::uuid AS id_1 FROM users WHERE id = ANY(@ids_1::uuid[]) OR id = @ids_1::uuid`))
+	require.ErrorIs(t, err, pgproto.ErrInputTypeConflict)
+	require.ErrorContains(t, err, "uuid[] and uuid")
+}
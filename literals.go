@@ -0,0 +1,85 @@
+package pgproto
+
+import (
+	"slices"
+	"strconv"
+
+	pgquery "github.com/pganalyze/pg_query_go/v6"
+	"google.golang.org/protobuf/proto"
+)
+
+// Literal describes a constant value that is hardcoded in the statement.
+type Literal struct {
+	Value    string
+	Type     TypeRef
+	Location int32
+}
+
+// Literals returns the constant values that are used in the predicates (WHERE, HAVING, JOIN, MERGE and ON CONFLICT
+// conditions) of the action, ordered by their location. These are candidates for being turned into inputs. The type
+// is inferred from the literal itself, e.g: 'active' is text and 1.5 is numeric.
+func Literals(action Action) (literals []Literal) {
+	seen := map[int32]bool{}
+	walkPredicates(action.info().stmt, func(msg proto.Message) bool {
+		cnst, ok := msg.(*pgquery.A_Const)
+		if !ok || cnst.GetIsnull() || seen[cnst.GetLocation()] {
+			return true
+		}
+
+		seen[cnst.GetLocation()] = true
+		literals = append(literals, literal(cnst))
+
+		return true
+	})
+
+	slices.SortFunc(literals, func(a, b Literal) int { return int(a.Location - b.Location) })
+
+	return literals
+}
+
+// walkPredicates walks the predicates (WHERE, HAVING, JOIN, MERGE and ON CONFLICT conditions) anywhere in the
+// statement. Predicates of sub-queries in a predicate are walked as part of that predicate, and again by themselves.
+func walkPredicates(stmt *pgquery.Node, fn func(msg proto.Message) bool) {
+	walk(stmt, func(msg proto.Message) bool {
+		switch node := msg.(type) {
+		case *pgquery.SelectStmt:
+			walk(node.GetWhereClause(), fn)
+			walk(node.GetHavingClause(), fn)
+		case *pgquery.UpdateStmt:
+			walk(node.GetWhereClause(), fn)
+		case *pgquery.DeleteStmt:
+			walk(node.GetWhereClause(), fn)
+		case *pgquery.InsertStmt:
+			walk(node.GetOnConflictClause().GetInfer().GetWhereClause(), fn)
+			walk(node.GetOnConflictClause().GetWhereClause(), fn)
+		case *pgquery.MergeStmt:
+			walk(node.GetJoinCondition(), fn)
+		case *pgquery.MergeWhenClause:
+			walk(node.GetCondition(), fn)
+		case *pgquery.JoinExpr:
+			walk(node.GetQuals(), fn)
+		}
+
+		return true
+	})
+}
+
+// literal describes the constant.
+func literal(cnst *pgquery.A_Const) Literal {
+	lit := Literal{Location: cnst.GetLocation()}
+
+	switch {
+	case cnst.GetSval() != nil:
+		lit.Value, lit.Type.Name = cnst.GetSval().GetSval(), "text"
+	case cnst.GetIval() != nil:
+		lit.Value, lit.Type.Name = strconv.Itoa(int(cnst.GetIval().GetIval())), "int4"
+	case cnst.GetFval() != nil:
+		lit.Value, lit.Type.Name = cnst.GetFval().GetFval(), "numeric"
+	case cnst.GetBoolval() != nil:
+		lit.Value, lit.Type.Name = strconv.FormatBool(cnst.GetBoolval().GetBoolval()), "bool"
+	case cnst.GetBsval() != nil:
+		lit.Value, lit.Type.Name = cnst.GetBsval().GetBsval(), "bit"
+	}
+
+	return lit
+}
@@ -0,0 +1,64 @@
+package pgproto_test
+
+import (
+	"testing"
+
+	"github.com/crewlinker/pgproto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLiterals(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`
+		SELECT id::uuid AS id_1, 'x'::text AS x_2 FROM users WHERE status = 'active' AND tenant = @tenant_1::uuid`))
+	require.NoError(t, err)
+	require.Equal(t, []pgproto.Literal{
+		{Value: "active", Type: pgproto.TypeRef{Name: "text"}, Location: 71},
+	}, pgproto.Literals(actions[0]))
+}
+
+func TestLiteralsInJoinsAndSubqueries(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`
+		UPDATE foo SET x = 1 WHERE id IN (
+			SELECT a.id FROM a JOIN b ON b.a_id = a.id AND b.n > 1.5 WHERE a.ok = true)`))
+	require.NoError(t, err)
+
+	lits := pgproto.Literals(actions[0])
+	require.Len(t, lits, 2)
+	require.Equal(t, "1.5", lits[0].Value)
+	require.Equal(t, "numeric", lits[0].Type.Name)
+	require.Equal(t, "true", lits[1].Value)
+	require.Equal(t, "bool", lits[1].Type.Name)
+}
+
+func TestLiteralsInMergeAndOnConflict(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		sql  string
+		exp  []string
+	}{
+		{
+			name: "merge join condition",
+			sql: `MERGE INTO stock t USING deliveries s ON t.item_id = s.item_id AND s.kind = 'bulk'
+				WHEN MATCHED AND s.qty > 10 THEN UPDATE SET qty = t.qty + s.qty`,
+			exp: []string{"bulk", "10"},
+		},
+		{
+			name: "on conflict where",
+			sql: `INSERT INTO users (email) VALUES (@email_1::text)
+				ON CONFLICT (email) WHERE deleted = false DO UPDATE SET seen = now() WHERE users.status = 'active'`,
+			exp: []string{"false", "active"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			actions, err := pgproto.ParseFullTyped([]byte(tt.sql))
+			require.NoError(t, err)
+
+			var values []string
+			for _, lit := range pgproto.Literals(actions[0]) {
+				values = append(values, lit.Value)
+			}
+
+			require.Equal(t, tt.exp, values)
+		})
+	}
+}
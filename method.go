@@ -0,0 +1,126 @@
+package pgproto
+
+import (
+	"strconv"
+
+	pgquery "github.com/pganalyze/pg_query_go/v6"
+)
+
+// actionKind returns a lower-case description of the kind of action.
+func actionKind(action Action) string {
+	switch action.(type) {
+	case *SelectAction:
+		return "select"
+	case *InsertAction:
+		return "insert"
+	case *UpdateAction:
+		return "update"
+	case *DeleteAction:
+		return "delete"
+	case *MergeAction:
+		return "merge"
+	default:
+		panicf(nil, "unsupported action: %T", action)
+
+		return ""
+	}
+}
+
+// MethodName returns a stable name for the action that can be used as an (exported) Go or protobuf method name. If
+// the statement was named with a "-- name:" comment that name is used. Otherwise the name is synthesized from the kind
+// of action and the first relation it references, e.g: "SelectFoo". Use [MethodNames] to name a batch of actions
+// without collisions.
+func MethodName(action Action) string {
+	info := action.info()
+	if info.Name != "" {
+		return camelCase(info.Name)
+	}
+
+	name := camelCase(actionKind(action))
+	if len(info.Relations) > 0 {
+		name += camelCase(info.Relations[0].Name)
+	}
+
+	return name
+}
+
+// MethodNames returns the [MethodName] of each action. Names that would otherwise be used more than once are
+// de-duplicated by suffixing them with a counter, e.g: "SelectFoo", "SelectFoo2".
+func MethodNames(actions []Action) []string {
+	names, used := make([]string, 0, len(actions)), map[string]bool{}
+	for _, action := range actions {
+		base := MethodName(action)
+
+		name := base
+		for n := 2; used[name]; n++ {
+			name = base + strconv.Itoa(n)
+		}
+
+		used[name] = true
+		names = append(names, name)
+	}
+
+	return names
+}
+
+// ConnectMethodInfo describes the RPC method that exposes an action, e.g: with Connect or gRPC.
+type ConnectMethodInfo struct {
+	// Name is the name of the method, see [MethodNames].
+	Name string
+	// ServerStreaming is set when the method streams a response message for each row, instead of returning a single
+	// response message.
+	ServerStreaming bool
+	// RequestMessage and ResponseMessage are the names of the messages the method receives and returns.
+	RequestMessage, ResponseMessage string
+}
+
+// ConnectMethods returns the description of the RPC method for each action, named as by [MethodNames] such that they
+// match the generated service. Selects that may return more than one row are server-streaming. A select is known to
+// return a single row when it is limited to one row ("LIMIT 1") or when it only outputs aggregates without grouping,
+// e.g: "SELECT count(*)::int8 AS n_1 FROM foo". A declared cardinality takes precedence, e.g: "-- name: ListUsers
+// :many" is always server-streaming.
+func ConnectMethods(actions []Action) []ConnectMethodInfo {
+	infos := make([]ConnectMethodInfo, 0, len(actions))
+	for idx, name := range MethodNames(actions) {
+		infos = append(infos, ConnectMethodInfo{
+			Name:            name,
+			ServerStreaming: serverStreaming(actions[idx]),
+			RequestMessage:  name + "Request",
+			ResponseMessage: name + "Response",
+		})
+	}
+
+	return infos
+}
+
+// serverStreaming returns whether the RPC method for the action streams its responses, see [ConnectMethods].
+func serverStreaming(a Action) bool {
+	switch sel, isSelect := a.(*SelectAction); {
+	case a.info().Cardinality != "":
+		return a.info().Cardinality == CardinalityMany
+	case isSelect:
+		return !returnsSingleRow(sel)
+	default:
+		return false
+	}
+}
+
+// returnsSingleRow returns whether the select is known to return at most a single row.
+func returnsSingleRow(sel *SelectAction) bool {
+	stmt := sel.stmt.GetSelectStmt()
+	if limit := stmt.GetLimitCount().GetAConst().GetIval(); limit != nil && limit.GetIval() <= 1 {
+		return true
+	}
+
+	if len(stmt.GetGroupClause()) > 0 || len(sel.Outputs) == 0 || stmt.GetOp() != pgquery.SetOperation_SETOP_NONE {
+		return false
+	}
+
+	for _, output := range sel.Outputs {
+		if !output.Aggregate {
+			return false
+		}
+	}
+
+	return true
+}
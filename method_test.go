@@ -0,0 +1,80 @@
+package pgproto_test
+
+import (
+	"testing"
+
+	"github.com/crewlinker/pgproto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMethodName(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`
+		-- name: get_user
+		SELECT id::uuid AS id_1 FROM users;
+		DELETE FROM kitchen_sinks;
+	`))
+	require.NoError(t, err)
+	require.Equal(t, "GetUser", pgproto.MethodName(actions[0]))
+	require.Equal(t, "DeleteKitchenSinks", pgproto.MethodName(actions[1]))
+}
+
+func TestMethodNamesDeduplicated(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`
+		SELECT id::uuid AS id_1 FROM foo;
+		SELECT name::text AS name_1 FROM foo;
+	`))
+	require.NoError(t, err)
+	require.Equal(t, "SelectFoo", pgproto.MethodName(actions[0]))
+	require.Equal(t, "SelectFoo", pgproto.MethodName(actions[1]))
+	require.Equal(t, []string{"SelectFoo", "SelectFoo2"}, pgproto.MethodNames(actions))
+}
+
+func TestConnectMethods(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`
+		-- name: list_users
+		SELECT id::uuid AS id_1 FROM users;
+		-- name: get_user
+		SELECT id::uuid AS id_1 FROM users WHERE id = @id_1::uuid LIMIT 1;
+		SELECT count(*)::int8 AS total_1 FROM users;
+		DELETE FROM users WHERE id = @id_1::uuid RETURNING id::uuid AS id_1;
+	`))
+	require.NoError(t, err)
+
+	require.Equal(t, pgproto.ConnectMethodInfo{
+		Name:            "ListUsers",
+		ServerStreaming: true,
+		RequestMessage:  "ListUsersRequest",
+		ResponseMessage: "ListUsersResponse",
+	}, pgproto.ConnectMethods(actions)[0])
+	require.False(t, pgproto.ConnectMethods(actions)[1].ServerStreaming)
+	require.False(t, pgproto.ConnectMethods(actions)[2].ServerStreaming)
+	require.False(t, pgproto.ConnectMethods(actions)[3].ServerStreaming)
+}
+
+func TestConnectMethodsSetOperation(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(
+		`SELECT count(*)::int8 AS n_1 FROM a UNION ALL SELECT count(*)::int8 AS n_1 FROM b`))
+	require.NoError(t, err)
+	require.True(t, pgproto.ConnectMethods(actions)[0].ServerStreaming)
+}
+
+func TestConnectMethodsDuplicateName(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1 FROM users;
+		SELECT name::text AS name_1 FROM users LIMIT 1;`))
+	require.NoError(t, err)
+
+	require.Equal(t, []pgproto.ConnectMethodInfo{{
+		Name:            "SelectUsers",
+		ServerStreaming: true,
+		RequestMessage:  "SelectUsersRequest",
+		ResponseMessage: "SelectUsersResponse",
+	}, {
+		Name:            "SelectUsers2",
+		RequestMessage:  "SelectUsers2Request",
+		ResponseMessage: "SelectUsers2Response",
+	}}, pgproto.ConnectMethods(actions))
+
+	src, err := pgproto.GenerateProto(actions, pgproto.ProtoOptions{ServiceName: "UserService"})
+	require.NoError(t, err)
+	require.Contains(t, string(src), "rpc SelectUsers2(SelectUsers2Request) returns (SelectUsers2Response);")
+}
@@ -0,0 +1,65 @@
+package pgproto
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// asciiIdentifier transliterates an identifier to ASCII, such that it is valid in generated code. Accents are removed
+// (e.g: "café" becomes "cafe") and other non-ASCII characters are replaced with an underscore.
+func asciiIdentifier(s string) string {
+	stripped, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), s)
+	if err != nil {
+		stripped = s
+	}
+
+	return strings.Map(func(r rune) rune {
+		if r > unicode.MaxASCII || (!unicode.IsLetter(r) && !unicode.IsDigit(r)) {
+			return '_'
+		}
+
+		return r
+	}, stripped)
+}
+
+// camelCase turns an identifier such as "kitchen_sinks" into "KitchenSinks". Existing upper-case letters are kept so
+// that names that are already camel-cased stay as is.
+func camelCase(s string) string {
+	var bldr strings.Builder
+	for _, part := range strings.FieldsFunc(asciiIdentifier(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		chars := []rune(part)
+		chars[0] = unicode.ToUpper(chars[0])
+		bldr.WriteString(string(chars))
+	}
+
+	return bldr.String()
+}
+
+// snakeCase turns an identifier such as "firstName" or "FirstName" into "first_name".
+func snakeCase(s string) string {
+	var bldr strings.Builder
+
+	chars := []rune(asciiIdentifier(s))
+	for i, r := range chars {
+		switch {
+		case unicode.IsUpper(r):
+			if i > 0 && (unicode.IsLower(chars[i-1]) || unicode.IsDigit(chars[i-1])) {
+				bldr.WriteRune('_')
+			}
+
+			bldr.WriteRune(unicode.ToLower(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			bldr.WriteRune(r)
+		default:
+			bldr.WriteRune('_')
+		}
+	}
+
+	return strings.Trim(bldr.String(), "_")
+}
@@ -0,0 +1,166 @@
+package pgproto
+
+import "regexp"
+
+// DomainInfo describes a domain type, such that casts to the domain can be treated as casts to its base type.
+type DomainInfo struct {
+	// Type is the base type of the domain.
+	Type TypeRef
+	// NotNull is set when the domain is declared with a NOT NULL constraint.
+	NotNull bool
+}
+
+// Options configure how SQL is parsed into actions.
+type Options struct {
+	// Docs holds documentation for outputs, keyed by their [Output.StableID].
+	Docs map[string]string
+	// NullableOverrides overrides the nullability of outputs, keyed by their [Output.StableID].
+	NullableOverrides map[string]bool
+	// CompositeOutputs allows outputs that cast a whole row to a composite type.
+	CompositeOutputs bool
+	// Domains holds domain types by their (qualified) name as used in casts.
+	Domains map[string]DomainInfo
+	// SuffixPattern matches the number suffix of names, the first capture group must capture the number. Defaults to
+	// matching an underscore followed by digits at the end of the name.
+	SuffixPattern *regexp.Regexp
+	// RequireContiguousInputNumbers requires the numbers of each action's inputs to be 1..n without gaps.
+	RequireContiguousInputNumbers bool
+	// RequireContiguousNumbers requires the numbers of each action's outputs to be 1..n without gaps.
+	RequireContiguousNumbers bool
+	// CanonicalTypeNames names built-in types by their canonical name, without schema.
+	CanonicalTypeNames bool
+	// DisallowStarAnywhere disallows "*" anywhere in the statement, not just in the result set.
+	DisallowStarAnywhere bool
+	// PositionalInputNumbering parses positional arguments ($1, $2, ...) as inputs, numbered by their position.
+	PositionalInputNumbering bool
+	// InputTypeInference infers the type of named arguments without a type cast from the expression they are
+	// compared to.
+	InputTypeInference bool
+	// AutoNumberByPosition numbers outputs whose alias has no number suffix by their position in the result set.
+	AutoNumberByPosition bool
+	// InferAliases names outputs that select a column without an alias after the column.
+	InferAliases bool
+	// RequireQualifiedRelations requires every table reference to be qualified with a schema.
+	RequireQualifiedRelations bool
+	// ActionHooks are called for each action after it is parsed and validated.
+	ActionHooks []func(action Action) error
+}
+
+// Option configures the parsing.
+type Option func(opts *Options)
+
+// WithDocs provides documentation for outputs, keyed by their [Output.StableID] (e.g: "select:3"). This allows
+// documentation to be kept separate from the SQL. Generators emit it as comments.
+func WithDocs(docs map[string]string) Option {
+	return func(opts *Options) { opts.Docs = docs }
+}
+
+// WithNullableOverrides overrides the nullability of outputs, keyed by their [Output.StableID] (e.g: "select:3").
+// This asserts nullability where it can't be derived from the SQL, e.g: an output that is known to never be NULL. It
+// is an error for an override to not match any output.
+func WithNullableOverrides(overrides map[string]bool) Option {
+	return func(opts *Options) { opts.NullableOverrides = overrides }
+}
+
+// WithCompositeOutputs allows outputs that cast a whole row to a composite type, e.g: "(foo.*)::foo AS row_1". Such
+// outputs are flagged with [Output.IsComposite].
+func WithCompositeOutputs() Option {
+	return func(opts *Options) { opts.CompositeOutputs = true }
+}
+
+// WithDomains registers domain types by their (qualified) name as used in casts, e.g: "email" or "public.email".
+// Outputs cast to a domain get the domain's base type, and are nullable unless the domain is declared NOT NULL.
+func WithDomains(domains map[string]DomainInfo) Option {
+	return func(opts *Options) { opts.Domains = domains }
+}
+
+// WithSuffixPattern configures how the number suffix of names is matched, the first capture group of the pattern must
+// capture the number. For example, `#(\d+)$` supports names like "id#1".
+func WithSuffixPattern(pattern *regexp.Regexp) Option {
+	return func(opts *Options) { opts.SuffixPattern = pattern }
+}
+
+// WithSuffixSeparator configures the separator between a name and its number suffix, e.g: "__" for names like
+// "user_id__1". It is a shorthand for [WithSuffixPattern]. It panics when the separator is empty, since names like
+// "user1" would then be ambiguous about where the name stops and the number starts.
+func WithSuffixSeparator(sep string) Option {
+	if sep == "" {
+		panic("pgproto: suffix separator must not be empty")
+	}
+
+	return WithSuffixPattern(regexp.MustCompile(regexp.QuoteMeta(sep) + `(\d+)$`))
+}
+
+// WithContiguousInputNumbers requires the numbers of each action's inputs to be 1..n without gaps. This matters when
+// inputs are mapped onto positional arguments ($1, $2, ...).
+func WithContiguousInputNumbers() Option {
+	return func(opts *Options) { opts.RequireContiguousInputNumbers = true }
+}
+
+// WithContiguousNumbers requires the numbers of each action's outputs to be 1..n without gaps, e.g: outputs numbered
+// 1 and 3 are rejected since 2 is missing. By default gaps are allowed, as they are for protobuf field numbers.
+func WithContiguousNumbers() Option {
+	return func(opts *Options) { opts.RequireContiguousNumbers = true }
+}
+
+// WithCanonicalTypeNames names the types of inputs and outputs by their canonical name, e.g: "id::integer",
+// "id::pg_catalog.int4" and "id::int4" all have the type "int4". Built-in types are not qualified with the
+// "pg_catalog" schema, while other types are kept as written.
+func WithCanonicalTypeNames() Option {
+	return func(opts *Options) { opts.CanonicalTypeNames = true }
+}
+
+// WithDisallowStarAnywhere disallows "*" anywhere in the statement, including sub-queries, instead of just in the
+// result set. Selecting all columns tends to make queries fragile as tables evolve.
+func WithDisallowStarAnywhere() Option {
+	return func(opts *Options) { opts.DisallowStarAnywhere = true }
+}
+
+// WithAutoNumberByPosition numbers outputs whose alias has no number suffix by their position in the result set,
+// e.g: "SELECT id::uuid AS id" gets number 1. Since the numbers change when columns are reordered, no protobuf can be
+// generated for such outputs.
+func WithAutoNumberByPosition() Option {
+	return func(opts *Options) { opts.AutoNumberByPosition = true }
+}
+
+// WithInferredAliases allows outputs that select a (type casted) column without an alias, e.g: "SELECT id::uuid".
+// The output is named after the column and numbered by its position in the result set, see
+// [WithAutoNumberByPosition]. Outputs that are computed by an expression still require an alias.
+func WithInferredAliases() Option {
+	return func(opts *Options) { opts.InferAliases = true }
+}
+
+// WithQualifiedRelations requires every table reference to be qualified with a schema, e.g: "public.users" instead
+// of "users". This prevents the generated code from depending on the search_path of the connection.
+func WithQualifiedRelations() Option {
+	return func(opts *Options) { opts.RequireQualifiedRelations = true }
+}
+
+// WithPositionalInputNumbering parses positional arguments (e.g: "$1::uuid") as inputs. Their position is used as
+// the number, so they don't need a number suffix. Named arguments still require one.
+func WithPositionalInputNumbering() Option {
+	return func(opts *Options) { opts.PositionalInputNumbering = true }
+}
+
+// WithInputTypeInference infers the type of named arguments without a type cast from the type casted expression they
+// are compared to, e.g: "@tenant_1" in "tenant_id::uuid = @tenant_1" gets the type "uuid". Arguments whose type can't
+// be inferred still require a type cast.
+func WithInputTypeInference() Option {
+	return func(opts *Options) { opts.InputTypeInference = true }
+}
+
+// WithActionHooks adds hooks that are called for each action after it is parsed and validated. Hooks may change the
+// action, e.g: to mark outputs as deprecated. If a hook returns an error the action is not returned and the error is
+// reported for its statement instead.
+func WithActionHooks(hooks ...func(action Action) error) Option {
+	return func(opts *Options) { opts.ActionHooks = append(opts.ActionHooks, hooks...) }
+}
+
+// newOptions returns the options that result from applying each option.
+func newOptions(opts ...Option) (options Options) {
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	return options
+}
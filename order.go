@@ -0,0 +1,113 @@
+package pgproto
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	pgquery "github.com/pganalyze/pg_query_go/v6"
+	"github.com/samber/lo"
+)
+
+// ErrDependencyCycle is returned when actions can't be ordered because they (indirectly) depend on each other.
+var ErrDependencyCycle = errors.New("dependency cycle")
+
+// OrderByDependency orders the actions such that an action that writes to a relation comes before the actions that
+// read from it, e.g: an insert into "users" comes before a select from "users". The ordering is rough since it is only
+// based on the [ActionInfo.Relations], actions that don't depend on each other keep their relative order.
+func OrderByDependency(actions []Action) ([]Action, error) {
+	written := make([][]string, len(actions))
+	for idx, action := range actions {
+		written[idx] = lo.Map(writtenRelations(action.info().stmt), func(rvar *pgquery.RangeVar, _ int) string {
+			return rvar.GetSchemaname() + "." + rvar.GetRelname()
+		})
+	}
+
+	// dependents[i] holds the actions that read a relation that action i writes to.
+	dependents, blockers := make([][]int, len(actions)), make([]int, len(actions))
+	for writer := range actions {
+		for reader, action := range actions {
+			if reader == writer || !slices.ContainsFunc(written[writer], func(relation string) bool {
+				return readsRelation(action, written[reader], relation)
+			}) {
+				continue
+			}
+
+			dependents[writer] = append(dependents[writer], reader)
+			blockers[reader]++
+		}
+	}
+
+	ordered, done := make([]Action, 0, len(actions)), make([]bool, len(actions))
+	for len(ordered) < len(actions) {
+		next := -1
+		for idx := range actions {
+			if !done[idx] && blockers[idx] == 0 {
+				next = idx
+
+				break
+			}
+		}
+
+		if next < 0 {
+			var stuck []string
+			for idx, name := range MethodNames(actions) {
+				if !done[idx] {
+					stuck = append(stuck, name)
+				}
+			}
+
+			return nil, fmt.Errorf("%w between: %v", ErrDependencyCycle, stuck)
+		}
+
+		done[next] = true
+		ordered = append(ordered, actions[next])
+
+		for _, dependent := range dependents[next] {
+			blockers[dependent]--
+		}
+	}
+
+	return ordered, nil
+}
+
+// readsRelation returns whether the action reads from the relation, the relations an action writes to itself are not
+// considered to be read.
+func readsRelation(action Action, ownWritten []string, relation string) bool {
+	if slices.Contains(ownWritten, relation) {
+		return false
+	}
+
+	return slices.ContainsFunc(action.info().Relations, func(rel Relation) bool {
+		return lo.FromPtr(rel.Schema)+"."+rel.Name == relation
+	})
+}
+
+// writtenRelations returns the relations that the statement writes to, including those written by data-modifying
+// statements in its WITH clause, e.g: "WITH d AS (DELETE FROM t RETURNING *) SELECT * FROM d" writes to "t".
+func writtenRelations(stmt *pgquery.Node) (rvars []*pgquery.RangeVar) {
+	var with *pgquery.WithClause
+
+	switch {
+	case stmt.GetSelectStmt() != nil:
+		with = stmt.GetSelectStmt().GetWithClause()
+	case stmt.GetInsertStmt() != nil:
+		with = stmt.GetInsertStmt().GetWithClause()
+		rvars = append(rvars, stmt.GetInsertStmt().GetRelation())
+	case stmt.GetUpdateStmt() != nil:
+		with = stmt.GetUpdateStmt().GetWithClause()
+		rvars = append(rvars, stmt.GetUpdateStmt().GetRelation())
+	case stmt.GetDeleteStmt() != nil:
+		with = stmt.GetDeleteStmt().GetWithClause()
+		rvars = append(rvars, stmt.GetDeleteStmt().GetRelation())
+	case stmt.GetMergeStmt() != nil:
+		with = stmt.GetMergeStmt().GetWithClause()
+		rvars = append(rvars, stmt.GetMergeStmt().GetRelation())
+	}
+
+	for _, cte := range with.GetCtes() {
+		rvars = append(rvars, writtenRelations(cte.GetCommonTableExpr().GetCtequery())...)
+	}
+
+	return rvars
+}
@@ -0,0 +1,41 @@
+package pgproto_test
+
+import (
+	"testing"
+
+	"github.com/crewlinker/pgproto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderByDependency(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`
+		SELECT id::int4 AS id_1 FROM users;
+		INSERT INTO users (name) VALUES (@name_1::text) RETURNING id::int4 AS id_1;
+		SELECT id::int4 AS id_1 FROM teams;`))
+	require.NoError(t, err)
+
+	ordered, err := pgproto.OrderByDependency(actions)
+	require.NoError(t, err)
+	require.Equal(t, []string{"InsertUsers", "SelectUsers", "SelectTeams"}, pgproto.MethodNames(ordered))
+}
+
+func TestOrderByDependencyCycle(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`
+		INSERT INTO a (id) SELECT id FROM b RETURNING id::int4 AS id_1;
+		INSERT INTO b (id) SELECT id FROM a RETURNING id::int4 AS id_1;`))
+	require.NoError(t, err)
+
+	_, err = pgproto.OrderByDependency(actions)
+	require.ErrorIs(t, err, pgproto.ErrDependencyCycle)
+}
+
+func TestOrderByDependencyDataModifyingCTE(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`
+		SELECT id::int4 AS id_1 FROM users;
+		WITH d AS (DELETE FROM users RETURNING id) SELECT d.id::int4 AS id_1 FROM d;`))
+	require.NoError(t, err)
+
+	ordered, err := pgproto.OrderByDependency(actions)
+	require.NoError(t, err)
+	require.Equal(t, []pgproto.Action{actions[1], actions[0]}, ordered)
+}
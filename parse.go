@@ -5,50 +5,208 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 
 	pgquery "github.com/pganalyze/pg_query_go/v6"
 	"github.com/samber/lo"
+	"google.golang.org/protobuf/proto"
 )
 
 // Output describe the output from an action.
 type Output struct {
-	Number int
-	Name   string
-	Type   TypeRef
+	Number   int
+	Name     string
+	BaseName string
+	Type     TypeRef
+	Doc      string
+	// IsComposite is set when a whole row is cast to a composite type, e.g: "(foo.*)::foo".
+	IsComposite bool
+	// Nullable is set when the output may be NULL. Outputs are not nullable by default, an output is marked as
+	// nullable with a trailing "?" in its (quoted) alias, e.g: `AS "nick_2?"`. Outputs computed by aggregates that
+	// return NULL without input rows, and outputs cast to domains without a NOT NULL constraint are nullable too.
+	Nullable bool
+	// IsArray is set when the output is cast to an array type, e.g: "::text[]". ArrayDims holds the number of
+	// dimensions of the array.
+	IsArray   bool
+	ArrayDims int
+	// Deprecated marks the output as deprecated in generated code.
+	Deprecated bool
+	// Aggregate is set when the output is computed by an aggregate function, e.g: "count(*)::int8".
+	Aggregate bool
+
+	scope      string
+	positional bool // numbered by its position in the result set, instead of by its suffix
+}
+
+// StableID returns an identifier for the output that doesn't change as long as the output keeps its number and the
+// action keeps its name. For actions without a "-- name:" the kind of action is used instead, e.g: "select:3". Such
+// ids are shared by the unnamed actions of the same kind, options keyed by an id that is shared are rejected.
+func (o *Output) StableID() string {
+	return o.scope + ":" + strconv.Itoa(o.Number)
 }
 
 // TypeRef references a type.
 type TypeRef struct {
 	Schema *string
 	Name   string
+	// TypeMods holds the type modifiers of the cast, e.g: [255] for "varchar(255)" or [10, 2] for "numeric(10, 2)".
+	// It is nil for types without modifiers. Postgres adds the fields of an interval as a (bitmask) modifier.
+	TypeMods []int
+}
+
+// String returns the (qualified) name of the type as it would be written in SQL, without its type modifiers.
+func (t TypeRef) String() string {
+	if t.Schema != nil {
+		return *t.Schema + "." + t.Name
+	}
+
+	return t.Name
 }
 
-// Action describes an action we support.
+// Relation references a table (or view) that is used by an action.
+type Relation struct {
+	Schema *string
+	Name   string
+	// Aliases lists the distinct aliases the relation is referenced by, e.g: both "a1" and "a2" in a self-join.
+	Aliases []string
+}
+
+// WarningCode identifies a kind of warning.
+type WarningCode string
+
+const (
+	// WarnAmbiguousColumn is reported when an output references a column without qualifying it with a relation while
+	// multiple relations are joined. Without the catalog we can't tell if this is ambiguous, but it often is.
+	WarnAmbiguousColumn WarningCode = "ambiguous_column"
+	// WarnImplicitCoercion is reported when a predicate compares a column to a literal that is not type casted. This
+	// relies on Postgres coercing the literal to the column's type, which may not be the type the author expected.
+	WarnImplicitCoercion WarningCode = "implicit_coercion"
+)
+
+// Warning describes a potential problem with a statement that doesn't prevent it from being parsed.
+type Warning struct {
+	Code     WarningCode
+	Location int32
+	Message  string
+}
+
+// Cardinality describes how many rows a query returns.
+type Cardinality string
+
+const (
+	// CardinalityOne is declared for queries that return a single row, e.g: "-- name: GetUser :one".
+	CardinalityOne Cardinality = "one"
+	// CardinalityMany is declared for queries that return any number of rows, e.g: "-- name: ListUsers :many".
+	CardinalityMany Cardinality = "many"
+	// CardinalityExec is declared for queries that are executed for their effect, e.g: "-- name: DeleteUser :exec".
+	CardinalityExec Cardinality = "exec"
+)
+
+// QueryMeta holds the metadata of a query that is declared by a "-- name: <Name> [:<Cardinality>]" comment.
+type QueryMeta struct {
+	Name        string
+	Cardinality Cardinality
+}
+
+// ActionInfo holds the information that is shared by all kinds of actions.
+type ActionInfo struct {
+	// Name is declared by a "-- name: <Name>" comment in front of the statement, it is empty if there is none.
+	Name string
+	// Cardinality is declared after the name, e.g: "-- name: GetUser :one". It is empty if there is none.
+	Cardinality Cardinality
+	// Relations lists the distinct relations referenced by the statement, in order of appearance.
+	Relations []Relation
+	// Warnings lists potential problems with the statement.
+	Warnings []Warning
+	// Comment holds the text of the block of comments that immediately precedes the statement, without comment markers
+	// and directives such as "-- name:". A blank line ends the block.
+	Comment string
+	// SQL is the original text of the statement, including the comments in front of it but without the surrounding
+	// whitespace and terminating semicolon.
+	SQL string
+
+	fingerprint string
+	stmt        *pgquery.Node
+	location    int32 // of the SQL in the input
+}
+
+func (i *ActionInfo) info() *ActionInfo { return i }
+
+// Fingerprint identifies the statement by its structure, it changes when the statement changes in a way that would
+// change the generated code. It doesn't change for formatting, comments (other than the "-- name:" directive) or the
+// values of constants.
+func (i ActionInfo) Fingerprint() string { return i.fingerprint }
+
+// QueryMeta returns the metadata that is declared by the "-- name:" comment in front of the statement.
+func (i ActionInfo) QueryMeta() QueryMeta { return QueryMeta{Name: i.Name, Cardinality: i.Cardinality} }
+
+// Action describes an action we support. It is implemented by pointers to the kinds of actions, e.g: *SelectAction,
+// since parsing fills in their [ActionInfo] and visitors receive the action itself (see [Action.Accept]). The values
+// (e.g: SelectAction{}) don't implement Action.
 type Action interface {
 	isAction()
+	info() *ActionInfo
+	Fingerprint() string
+	QueryMeta() QueryMeta
+	Accept(v Visitor) error
+	getInputs() []*Input
 	getOutputs() []*Output
 }
 
 type (
 	// SelectAction describes an action that selects data.
 	SelectAction struct {
+		ActionInfo
+		Inputs  []*Input
 		Outputs []*Output
+		// HasLimit is set when the number of rows is limited with "LIMIT n" or "FETCH FIRST n ROWS ONLY". Note that
+		// "LIMIT ALL" doesn't limit the number of rows.
+		HasLimit bool
+		// SelfJoin is set when the same relation appears more than once in the FROM clause.
+		SelfJoin bool
 	}
 
 	// UpdateAction describes an action of updating data.
 	UpdateAction struct {
+		ActionInfo
+		Inputs  []*Input
 		Outputs []*Output
+		// DefaultedColumns lists the columns that are SET to their DEFAULT.
+		DefaultedColumns []string
+		// UsesCursor is set when the row to update is the current row of a cursor: "WHERE CURRENT OF <cursor>".
+		UsesCursor bool
 	}
 
 	// InsertAction describes an action of inserting data.
 	InsertAction struct {
+		ActionInfo
+		Inputs  []*Input
 		Outputs []*Output
+		// Overriding is "SYSTEM" or "USER" when the insert overrides identity columns with "OVERRIDING SYSTEM VALUE"
+		// or "OVERRIDING USER VALUE". It is empty otherwise.
+		Overriding string
+		// OnConflict describes the "ON CONFLICT" clause of an upsert, it is nil for plain inserts.
+		OnConflict *OnConflictInfo
 	}
 
 	// DeleteAction describes an action of deleting data.
 	DeleteAction struct {
+		ActionInfo
+		Inputs  []*Input
+		Outputs []*Output
+	}
+
+	// MergeAction describes an action of merging data with "MERGE INTO ... USING ...". Since Postgres 17 a merge
+	// can return the rows it changed with "RETURNING".
+	MergeAction struct {
+		ActionInfo
+		Inputs  []*Input
 		Outputs []*Output
 	}
 )
@@ -57,10 +215,17 @@ func (SelectAction) isAction()               {}
 func (UpdateAction) isAction()               {}
 func (InsertAction) isAction()               {}
 func (DeleteAction) isAction()               {}
+func (MergeAction) isAction()                {}
+func (a SelectAction) getInputs() []*Input   { return a.Inputs }
+func (a UpdateAction) getInputs() []*Input   { return a.Inputs }
+func (a InsertAction) getInputs() []*Input   { return a.Inputs }
+func (a DeleteAction) getInputs() []*Input   { return a.Inputs }
+func (a MergeAction) getInputs() []*Input    { return a.Inputs }
 func (a SelectAction) getOutputs() []*Output { return a.Outputs }
 func (a UpdateAction) getOutputs() []*Output { return a.Outputs }
 func (a InsertAction) getOutputs() []*Output { return a.Outputs }
 func (a DeleteAction) getOutputs() []*Output { return a.Outputs }
+func (a MergeAction) getOutputs() []*Output  { return a.Outputs }
 
 // ErrNoColumnAliasUsed is returned when parsing a result target but it has no explicitly named with an alias.
 var ErrNoColumnAliasUsed = errors.New(`no alias for column in result set, use "AS" to define the alias`)
@@ -71,6 +236,13 @@ var ErrColumnWithoutCast = errors.New(`no type cast for column in result set, us
 // ErrTypeCastInvalid is returned when the type cast is invalid.
 var ErrTypeCastInvalid = errors.New(`invalid type cast for column, must be "::<name>" or "::<schema>.<name>" `)
 
+// ErrStarNotAllowed is returned when the result set uses "*" (including the "TABLE <name>" shorthand) instead of
+// listing explicitly typed columns.
+var ErrStarNotAllowed = errors.New(`"*" is not allowed in the result set, list each column with a type cast and alias`)
+
+// ErrCompositeOutput is returned when a whole row is cast to a composite type while that is not enabled.
+var ErrCompositeOutput = errors.New(`row cast to a composite type, enable composite outputs to allow this`)
+
 // svalString returns a string.
 func svalString(n interface{ GetString_() *pgquery.String }) string {
 	str := n.GetString_()
@@ -81,26 +253,102 @@ func svalString(n interface{ GetString_() *pgquery.String }) string {
 	return str.GetSval()
 }
 
-func parseResultTarget(stmt interface{ GetResTarget() *pgquery.ResTarget }) (out *Output, err error) {
+// isStar returns whether the node is a column reference ending in "*", e.g: "*" or "foo.*".
+func isStar(node *pgquery.Node) bool {
+	return isStarRef(node.GetColumnRef())
+}
+
+// isStarRef returns whether the column reference ends in "*".
+func isStarRef(ref *pgquery.ColumnRef) bool {
+	fields := ref.GetFields()
+
+	return len(fields) > 0 && fields[len(fields)-1].GetAStar() != nil
+}
+
+// cteNames returns the names of the common table expressions (e.g: "WITH foo AS (...)") declared in the statement.
+func cteNames(stmt *pgquery.Node) map[string]bool {
+	ctes := map[string]bool{}
+	walk(stmt, func(msg proto.Message) bool {
+		if cte, ok := msg.(*pgquery.CommonTableExpr); ok {
+			ctes[cte.GetCtename()] = true
+		}
+
+		return true
+	})
+
+	return ctes
+}
+
+// ErrRelationNotQualified is returned when a table is referenced without a schema while that is required.
+var ErrRelationNotQualified = errors.New("relation not qualified with a schema")
+
+// checkQualifiedRelations checks that every table reference is qualified with a schema. References to common table
+// expressions can't be qualified and are allowed.
+func checkQualifiedRelations(stmt *pgquery.Node) (err error) {
+	ctes := cteNames(stmt)
+	walk(stmt, func(msg proto.Message) bool {
+		rvar, ok := msg.(*pgquery.RangeVar)
+		if ok && err == nil && rvar.GetSchemaname() == "" && !ctes[rvar.GetRelname()] {
+			err = positionErrorf("relation", rvar.GetLocation(), "%w: %s", ErrRelationNotQualified, rvar.GetRelname())
+		}
+
+		return err == nil
+	})
+
+	return err
+}
+
+// checkNoStar checks that "*" isn't used anywhere in the statement, including sub-queries. Aggregates such as
+// "count(*)" don't select columns and are allowed.
+func checkNoStar(stmt *pgquery.Node) (err error) {
+	walk(stmt, func(msg proto.Message) bool {
+		if ref, ok := msg.(*pgquery.ColumnRef); ok && err == nil && isStarRef(ref) {
+			err = positionErrorf("star", ref.GetLocation(), "%w", ErrStarNotAllowed)
+		}
+
+		return err == nil
+	})
+
+	return err
+}
+
+func parseResultTarget(
+	stmt interface{ GetResTarget() *pgquery.ResTarget }, position int, opts Options,
+) (out *Output, err error) {
 	rtgt := stmt.GetResTarget()
 	if rtgt == nil {
 		panicf(nil, "no result target")
 	}
 
-	// @TODO better errors by menting the column name. as in for SELECT id FROM foo; it should mention 'id'
-	// in the error. For furthe errors. BUT this requires de-nesting the casting stack, check for a troublesome
-	// example in the "double_result_cast.sql" example. If we have that, we should replace column '%s' shown
-	// below to mention the column name, not the alias.
+	if isStar(rtgt.GetVal()) {
+		return nil, resTargetErrorf(rtgt, "%w", ErrStarNotAllowed)
+	}
+
+	name, annotatedNullable := strings.CutSuffix(rtgt.GetName(), nullableMarker)
+	out = &Output{Name: name}
+
+	switch inferred := inferredAlias(rtgt.GetVal()); {
+	case out.Name == "" && opts.InferAliases && inferred != "":
+		out.Name, out.BaseName, out.Number, out.positional = inferred, inferred, position, true
+	case out.Name == "":
+		return nil, resTargetErrorf(rtgt, "%s%w", describeColumn(rtgt.GetVal()), ErrNoColumnAliasUsed)
+	default:
+		out.BaseName, out.Number, err = numberedName(out.Name, opts.SuffixPattern)
+		if errors.Is(err, ErrNamedWithoutNumberSuffix) && opts.AutoNumberByPosition {
+			out.BaseName, out.Number, out.positional, err = out.Name, position, true, nil
+		}
 
-	out = &Output{}
-	out.Name = rtgt.GetName()
-	if out.Name == "" {
-		return nil, resTargetErrorf(rtgt, "%w", ErrNoColumnAliasUsed)
+		if err != nil {
+			return nil, resTargetErrorf(rtgt, "%w", err)
+		}
 	}
 
-	out.Number, err = numberedName(out.Name)
-	if err != nil {
-		return nil, resTargetErrorf(rtgt, "%w", err)
+	if !validBaseName(out.BaseName) {
+		return nil, resTargetErrorf(rtgt, "alias '%s': %w", out.Name, ErrInvalidBaseName)
+	}
+
+	if isReservedFieldNumber(out.Number) {
+		return nil, resTargetErrorf(rtgt, "alias '%s': %w: %d", out.Name, ErrReservedFieldNumber, out.Number)
 	}
 
 	val := rtgt.GetVal()
@@ -110,7 +358,7 @@ func parseResultTarget(stmt interface{ GetResTarget() *pgquery.ResTarget }) (out
 
 	cast := val.GetTypeCast()
 	if cast == nil {
-		return nil, resTargetErrorf(rtgt, "alias '%s': %w", out.Name, ErrColumnWithoutCast)
+		return nil, resTargetErrorf(rtgt, "alias '%s': %s%w", out.Name, describeColumn(val), ErrColumnWithoutCast)
 	}
 
 	typeName := cast.GetTypeName()
@@ -118,33 +366,385 @@ func parseResultTarget(stmt interface{ GetResTarget() *pgquery.ResTarget }) (out
 		panicf(val, "type cast without type name")
 	}
 
+	if isStar(cast.GetArg()) {
+		if !opts.CompositeOutputs {
+			return nil, resTargetErrorf(rtgt, "alias '%s': %w", out.Name, ErrCompositeOutput)
+		}
+
+		out.IsComposite = true
+	}
+
+	out.Type, err = parseTypeName(typeName)
+	if err != nil {
+		return nil, resTargetErrorf(rtgt, "alias '%s': %w", out.Name, err)
+	}
+
+	out.Type = canonicalType(out.Type, opts)
+
+	out.ArrayDims = len(typeName.GetArrayBounds())
+	out.IsArray = out.ArrayDims > 0
+	out.Aggregate = isAggregate(castedFuncCall(cast))
+	out.Nullable = isNullableAggregate(castedFuncCall(cast))
+
+	if domain, ok := opts.Domains[out.Type.String()]; ok {
+		out.Type, out.Nullable = domain.Type.clone(), !domain.NotNull
+	}
+
+	out.Nullable = out.Nullable || annotatedNullable
+
+	return out, nil
+}
+
+// sourceColumn returns the (qualified) name of the column that the value is computed from, looking through casts and
+// operators, e.g: "e.salary" for "CAST(CAST(e.salary AS numeric) AS text)". For operators the first column is returned.
+// It returns an empty string if no column is found.
+func sourceColumn(val *pgquery.Node) string {
+	switch {
+	case val.GetTypeCast() != nil:
+		return sourceColumn(val.GetTypeCast().GetArg())
+	case val.GetAExpr() != nil:
+		if name := sourceColumn(val.GetAExpr().GetLexpr()); name != "" {
+			return name
+		}
+
+		return sourceColumn(val.GetAExpr().GetRexpr())
+	case val.GetColumnRef() != nil && !isStar(val):
+		names := make([]string, 0, len(val.GetColumnRef().GetFields()))
+		for _, field := range val.GetColumnRef().GetFields() {
+			names = append(names, svalString(field))
+		}
+
+		return strings.Join(names, ".")
+	default:
+		return ""
+	}
+}
+
+// describeColumn returns a description of the column the value is computed from for use in errors, e.g: "column
+// 'id': ". It returns an empty string if no column is found.
+func describeColumn(val *pgquery.Node) string {
+	if name := sourceColumn(val); name != "" {
+		return fmt.Sprintf("column '%s': ", name)
+	}
+
+	return ""
+}
+
+// nullableMarker is the suffix of an alias that marks the output as nullable, e.g: `AS "nick_2?"`.
+const nullableMarker = "?"
+
+// inferredAlias returns the name of the column that is (type casted and) selected, e.g: "id" for "u.id::uuid". It
+// returns an empty string if the value is not a column reference.
+func inferredAlias(val *pgquery.Node) string {
+	for val.GetTypeCast() != nil {
+		val = val.GetTypeCast().GetArg()
+	}
+
+	fields := val.GetColumnRef().GetFields()
+	if len(fields) == 0 {
+		return ""
+	}
+
+	return fields[len(fields)-1].GetString_().GetSval()
+}
+
+// parseTypeName parses the (optionally schema qualified) name of the type in a type cast.
+func parseTypeName(typeName *pgquery.TypeName) (typ TypeRef, err error) {
 	typeNameParts := typeName.GetNames()
+	for _, part := range typeNameParts {
+		partStr := part.GetString_()
+		if partStr == nil {
+			panicf(part, "type cast name part is not a string")
+		}
+	}
+
 	switch len(typeNameParts) {
 	case 1: // not fully qualified, e.g:  SELECT '123'::int4;
-		out.Type.Name = svalString(typeNameParts[0])
+		typ.Name = svalString(typeNameParts[0])
 	case 2: // fully qualified, e.g:      SELECT '123'::pg_catalog.int4;
 		schemaStr := svalString(typeNameParts[0])
-		out.Type.Schema = &schemaStr
-		out.Type.Name = svalString(typeNameParts[1])
+		typ.Schema = &schemaStr
+		typ.Name = svalString(typeNameParts[1])
 	default:
-		return nil, resTargetErrorf(rtgt, "alias '%s': %w, number of parts: %d",
-			out.Name, ErrTypeCastInvalid, len(typeNameParts))
+		return typ, fmt.Errorf("%w, number of parts: %d: %s", ErrTypeCastInvalid, len(typeNameParts),
+			strings.Join(lo.Map(typeNameParts, func(part *pgquery.Node, _ int) string { return svalString(part) }), "."))
 	}
 
-	for _, part := range typeName.GetNames() {
-		partStr := part.GetString_()
-		if partStr == nil {
-			panicf(part, "type cast name part is not a string")
+	for _, mod := range typeName.GetTypmods() {
+		if ival := mod.GetAConst().GetIval(); ival != nil {
+			typ.TypeMods = append(typ.TypeMods, int(ival.GetIval()))
 		}
 	}
 
-	return out, nil
+	return typ, nil
 }
 
-func parseSelectStmt(stmt *pgquery.SelectStmt) (action *SelectAction, err error) {
+// parseSelectStmt parses a select, its outputs are those of the main select. Common table expressions only feed the
+// main select, so the RETURNING outputs of data-modifying ones (e.g: "WITH del AS (DELETE ... RETURNING id)") are
+// ignored and need no alias or type cast.
+func parseSelectStmt(stmt *pgquery.SelectStmt, opts Options) (action *SelectAction, err error) {
 	action = &SelectAction{}
+	action.Inputs, err = parseInputs(stmt, opts)
+
+	for idx, arm := range selectArms(stmt) {
+		outputs, perr := parseSelectTargets(arm, opts)
+		switch {
+		case perr != nil:
+			err = errors.Join(err, perr)
+		case idx == 0:
+			action.Outputs = outputs
+		default:
+			if merr := mergeArmOutputs(action.Outputs, outputs); merr != nil {
+				err = errors.Join(err, fmt.Errorf("set operation arm %d: %w", idx+1, merr))
+			}
+		}
+
+		action.Warnings = append(action.Warnings, ambiguousColumnWarnings(arm)...)
+	}
+
+	action.HasLimit = hasLimit(stmt)
+	action.SelfJoin = isSelfJoin(stmt)
+
+	return
+}
+
+// parseSelectTargets parses the outputs of a select from its target list.
+func parseSelectTargets(stmt *pgquery.SelectStmt, opts Options) (outputs []*Output, err error) {
+	for idx, target := range stmt.GetTargetList() {
+		output, perr := parseResultTarget(target, idx+1, opts)
+		if perr != nil {
+			err = errors.Join(err, perr)
+
+			continue
+		}
+
+		outputs = append(outputs, output)
+	}
+
+	return outputs, err
+}
+
+// selectArms returns the selects that are combined by set operations (e.g: UNION), in order. The target list of a
+// set operation itself is empty. A select without set operation is its own single arm.
+func selectArms(stmt *pgquery.SelectStmt) []*pgquery.SelectStmt {
+	if stmt.GetOp() == pgquery.SetOperation_SETOP_NONE {
+		return []*pgquery.SelectStmt{stmt}
+	}
+
+	return append(selectArms(stmt.GetLarg()), selectArms(stmt.GetRarg())...)
+}
+
+// ErrSetOperationMismatch is returned when the arms of a set operation (e.g: UNION) don't agree on their outputs.
+var ErrSetOperationMismatch = errors.New("arms of set operation have different outputs")
+
+// mergeArmOutputs checks that the outputs of another arm of a set operation have the same aliases and types as the
+// outputs of the first arm. Outputs are nullable if they are nullable in any of the arms.
+func mergeArmOutputs(outputs, arm []*Output) error {
+	if len(arm) != len(outputs) {
+		return fmt.Errorf("%w: %d outputs instead of %d", ErrSetOperationMismatch, len(arm), len(outputs))
+	}
+
+	for idx, output := range outputs {
+		other := arm[idx]
+		if other.Name != output.Name || other.Type.String() != output.Type.String() || other.ArrayDims != output.ArrayDims {
+			return fmt.Errorf("%w: output '%s' of type %s does not match '%s' of type %s",
+				ErrSetOperationMismatch, other.Name, other.Type, output.Name, output.Type)
+		}
+
+		output.Nullable = output.Nullable || other.Nullable
+	}
+
+	return nil
+}
+
+// isSelfJoin returns whether a relation is joined with itself, relations in subqueries are not considered.
+func isSelfJoin(stmt *pgquery.SelectStmt) (selfJoin bool) {
+	seen := map[string]bool{}
+	for _, from := range stmt.GetFromClause() {
+		walk(from, func(msg proto.Message) bool {
+			switch node := msg.(type) {
+			case *pgquery.RangeSubselect, *pgquery.SubLink:
+				return false
+			case *pgquery.RangeVar:
+				key := node.GetSchemaname() + "." + node.GetRelname()
+				selfJoin = selfJoin || seen[key]
+				seen[key] = true
+			}
+
+			return true
+		})
+	}
+
+	return selfJoin
+}
+
+// hasLimit returns whether the number of selected rows is limited. Postgres parses "FETCH FIRST" the same as "LIMIT"
+// and "LIMIT ALL" the same as "LIMIT NULL", which doesn't limit anything.
+func hasLimit(stmt *pgquery.SelectStmt) bool {
+	count := stmt.GetLimitCount()
+	if count == nil {
+		return false
+	}
+
+	if cnst := count.GetAConst(); cnst != nil && cnst.GetIsnull() {
+		return false
+	}
+
+	return true
+}
+
+// ambiguousColumnWarnings warns about unqualified column references in the result set when multiple relations are
+// selected from.
+func ambiguousColumnWarnings(stmt *pgquery.SelectStmt) (warnings []Warning) {
+	if countFromItems(stmt.GetFromClause()) < 2 {
+		return nil
+	}
+
 	for _, target := range stmt.GetTargetList() {
-		output, perr := parseResultTarget(target)
+		walk(target, func(msg proto.Message) bool {
+			switch node := msg.(type) {
+			case *pgquery.SubLink:
+				return false // sub queries have their own scope
+			case *pgquery.ColumnRef:
+				if fields := node.GetFields(); len(fields) == 1 && fields[0].GetString_() != nil {
+					warnings = append(warnings, Warning{
+						Code:     WarnAmbiguousColumn,
+						Location: node.GetLocation(),
+						Message: fmt.Sprintf("column '%s' is not qualified while selecting from multiple relations",
+							svalString(fields[0])),
+					})
+				}
+			}
+
+			return true
+		})
+	}
+
+	return warnings
+}
+
+// comparisonOperators are the operators for which implicit coercion of literals is reported.
+var comparisonOperators = map[string]bool{
+	"=": true, "<>": true, "!=": true, "<": true, ">": true, "<=": true, ">=": true,
+}
+
+// implicitCoercionWarnings warns about predicates that compare a column to a literal without a type cast.
+func implicitCoercionWarnings(stmt *pgquery.Node) (warnings []Warning) {
+	seen := map[int32]bool{}
+	walkPredicates(stmt, func(msg proto.Message) bool {
+		expr, ok := msg.(*pgquery.A_Expr)
+		if !ok || expr.GetKind() != pgquery.A_Expr_Kind_AEXPR_OP || len(expr.GetName()) != 1 ||
+			!comparisonOperators[svalString(expr.GetName()[0])] || seen[expr.GetLocation()] {
+			return true
+		}
+
+		column, cnst := expr.GetLexpr().GetColumnRef(), expr.GetRexpr().GetAConst()
+		if column == nil || cnst == nil {
+			column, cnst = expr.GetRexpr().GetColumnRef(), expr.GetLexpr().GetAConst()
+		}
+
+		if column == nil || cnst == nil || cnst.GetIsnull() || cnst.GetBoolval() != nil {
+			return true
+		}
+
+		seen[expr.GetLocation()] = true
+		warnings = append(warnings, Warning{
+			Code:     WarnImplicitCoercion,
+			Location: expr.GetLocation(),
+			Message: fmt.Sprintf("column '%s' is compared to literal '%s' without a type cast",
+				columnRefName(column), literal(cnst).Value),
+		})
+
+		return true
+	})
+
+	return warnings
+}
+
+// columnRefName returns the (qualified) name of the referenced column as it would be written in SQL.
+func columnRefName(ref *pgquery.ColumnRef) string {
+	parts := make([]string, 0, len(ref.GetFields()))
+	for _, field := range ref.GetFields() {
+		if field.GetAStar() != nil {
+			parts = append(parts, "*")
+		} else {
+			parts = append(parts, svalString(field))
+		}
+	}
+
+	return strings.Join(parts, ".")
+}
+
+// countFromItems counts the relations, sub-queries and functions that are selected from, including those joined.
+func countFromItems(items []*pgquery.Node) (n int) {
+	for _, item := range items {
+		if join := item.GetJoinExpr(); join != nil {
+			n += countFromItems([]*pgquery.Node{join.GetLarg(), join.GetRarg()})
+
+			continue
+		}
+
+		n++
+	}
+
+	return n
+}
+
+// OnConflictInfo describes the "ON CONFLICT" clause of an insert.
+type OnConflictInfo struct {
+	// Action is "NOTHING" for "DO NOTHING" and "UPDATE" for "DO UPDATE SET ...".
+	Action string
+	// Columns lists the names of the columns of the conflict target, e.g: "email" for "ON CONFLICT (email)". Index
+	// expressions are not listed.
+	Columns []string
+	// Constraint is the name of the constraint for "ON CONFLICT ON CONSTRAINT <name>".
+	Constraint string
+	// SetColumns lists the columns that are updated by "DO UPDATE SET ...".
+	SetColumns []string
+}
+
+// parseOnConflict returns the description of the "ON CONFLICT" clause, or nil if the insert doesn't have one.
+func parseOnConflict(clause *pgquery.OnConflictClause) *OnConflictInfo {
+	if clause == nil {
+		return nil
+	}
+
+	info := &OnConflictInfo{Constraint: clause.GetInfer().GetConname()}
+
+	switch clause.GetAction() { //nolint:exhaustive
+	case pgquery.OnConflictAction_ONCONFLICT_NOTHING:
+		info.Action = "NOTHING"
+	case pgquery.OnConflictAction_ONCONFLICT_UPDATE:
+		info.Action = "UPDATE"
+	}
+
+	for _, elem := range clause.GetInfer().GetIndexElems() {
+		if name := elem.GetIndexElem().GetName(); name != "" {
+			info.Columns = append(info.Columns, name)
+		}
+	}
+
+	for _, target := range clause.GetTargetList() {
+		info.SetColumns = append(info.SetColumns, target.GetResTarget().GetName())
+	}
+
+	return info
+}
+
+func parseInsertStmt(stmt *pgquery.InsertStmt, opts Options) (action *InsertAction, err error) {
+	action = &InsertAction{}
+	action.Inputs, err = parseInputs(stmt, opts)
+	action.OnConflict = parseOnConflict(stmt.GetOnConflictClause())
+
+	switch stmt.GetOverride() { //nolint:exhaustive
+	case pgquery.OverridingKind_OVERRIDING_SYSTEM_VALUE:
+		action.Overriding = "SYSTEM"
+	case pgquery.OverridingKind_OVERRIDING_USER_VALUE:
+		action.Overriding = "USER"
+	}
+
+	for idx, returning := range stmt.GetReturningList() {
+		output, perr := parseResultTarget(returning, idx+1, opts)
 		if perr != nil {
 			err = errors.Join(err, perr)
 
@@ -157,10 +757,12 @@ func parseSelectStmt(stmt *pgquery.SelectStmt) (action *SelectAction, err error)
 	return
 }
 
-func parseInsertStmt(stmt *pgquery.InsertStmt) (action *InsertAction, err error) {
-	action = &InsertAction{}
-	for _, returning := range stmt.GetReturningList() {
-		output, perr := parseResultTarget(returning)
+func parseDeleteStmt(stmt *pgquery.DeleteStmt, opts Options) (action *DeleteAction, err error) {
+	action = &DeleteAction{}
+	action.Inputs, err = parseInputs(stmt, opts)
+
+	for idx, returning := range stmt.GetReturningList() {
+		output, perr := parseResultTarget(returning, idx+1, opts)
 		if perr != nil {
 			err = errors.Join(err, perr)
 
@@ -173,10 +775,12 @@ func parseInsertStmt(stmt *pgquery.InsertStmt) (action *InsertAction, err error)
 	return
 }
 
-func parseDeleteStmt(stmt *pgquery.DeleteStmt) (action *DeleteAction, err error) {
-	action = &DeleteAction{}
-	for _, returning := range stmt.GetReturningList() {
-		output, perr := parseResultTarget(returning)
+func parseMergeStmt(stmt *pgquery.MergeStmt, opts Options) (action *MergeAction, err error) {
+	action = &MergeAction{}
+	action.Inputs, err = parseInputs(stmt, opts)
+
+	for idx, returning := range stmt.GetReturningList() {
+		output, perr := parseResultTarget(returning, idx+1, opts)
 		if perr != nil {
 			err = errors.Join(err, perr)
 
@@ -189,10 +793,20 @@ func parseDeleteStmt(stmt *pgquery.DeleteStmt) (action *DeleteAction, err error)
 	return
 }
 
-func parseUpdateStmt(stmt *pgquery.UpdateStmt) (action *UpdateAction, err error) {
+func parseUpdateStmt(stmt *pgquery.UpdateStmt, opts Options) (action *UpdateAction, err error) {
 	action = &UpdateAction{}
-	for _, returning := range stmt.GetReturningList() {
-		output, perr := parseResultTarget(returning)
+	action.Inputs, err = parseInputs(stmt, opts)
+
+	for _, target := range stmt.GetTargetList() {
+		if target.GetResTarget().GetVal().GetSetToDefault() != nil {
+			action.DefaultedColumns = append(action.DefaultedColumns, target.GetResTarget().GetName())
+		}
+	}
+
+	action.UsesCursor = stmt.GetWhereClause().GetCurrentOfExpr() != nil
+
+	for idx, returning := range stmt.GetReturningList() {
+		output, perr := parseResultTarget(returning, idx+1, opts)
 		if perr != nil {
 			err = errors.Join(err, perr)
 
@@ -202,13 +816,16 @@ func parseUpdateStmt(stmt *pgquery.UpdateStmt) (action *UpdateAction, err error)
 		action.Outputs = append(action.Outputs, output)
 	}
 
-	return action, nil
+	return action, err
 }
 
 // ErrDuplicateNumberSuffix is returned when a number suffix for a name is used twice.
 var ErrDuplicateNumberSuffix = errors.New("duplicate number suffix")
 
-func checkAction(action Action) error {
+// ErrNonContiguousNumbers is returned when numbers are required to be contiguous (1..n) but there are gaps.
+var ErrNonContiguousNumbers = errors.New("numbers are not contiguous")
+
+func checkAction(action Action, opts Options) error {
 	outputsByNumber := map[int]*Output{}
 	for _, output := range action.getOutputs() {
 		if existing, exists := outputsByNumber[output.Number]; exists {
@@ -218,41 +835,146 @@ func checkAction(action Action) error {
 		outputsByNumber[output.Number] = output
 	}
 
+	if opts.RequireContiguousInputNumbers {
+		if err := checkContiguous(lo.Map(action.getInputs(), func(in *Input, _ int) int { return in.Number })); err != nil {
+			return fmt.Errorf("inputs: %w", err)
+		}
+	}
+
+	if opts.RequireContiguousNumbers {
+		if err := checkContiguous(lo.Keys(outputsByNumber)); err != nil {
+			return fmt.Errorf("outputs: %w", err)
+		}
+	}
+
 	return nil
 }
 
-func parseStmt(rstmt *pgquery.RawStmt) (action Action, err error) {
+// checkContiguous checks that the numbers are 1..n without gaps.
+func checkContiguous(numbers []int) error {
+	used := lo.SliceToMap(numbers, func(n int) (int, bool) { return n, true })
+	for n := 1; n <= len(numbers); n++ {
+		if !used[n] {
+			return fmt.Errorf("%w, missing: %d", ErrNonContiguousNumbers, n)
+		}
+	}
+
+	return nil
+}
+
+// parseRelations collects the distinct relations referenced anywhere in the statement. References to common table
+// expressions are not relations and are left out.
+func parseRelations(stmt *pgquery.Node) (rels []Relation) {
+	seen, ctes := map[string]int{}, cteNames(stmt)
+	walk(stmt, func(msg proto.Message) bool {
+		rvar, ok := msg.(*pgquery.RangeVar)
+		if !ok || (rvar.GetSchemaname() == "" && ctes[rvar.GetRelname()]) {
+			return true
+		}
+
+		key := rvar.GetSchemaname() + "." + rvar.GetRelname()
+
+		idx, exists := seen[key]
+		if !exists {
+			rel := Relation{Name: rvar.GetRelname()}
+			if schema := rvar.GetSchemaname(); schema != "" {
+				rel.Schema = &schema
+			}
+
+			idx, seen[key] = len(rels), len(rels)
+			rels = append(rels, rel)
+		}
+
+		if alias := rvar.GetAlias().GetAliasname(); alias != "" && !slices.Contains(rels[idx].Aliases, alias) {
+			rels[idx].Aliases = append(rels[idx].Aliases, alias)
+		}
+
+		return true
+	})
+
+	return rels
+}
+
+func parseStmt(
+	rstmt *pgquery.RawStmt, sql string, comments []string, comment string, opts Options,
+) (action Action, err error) {
 	stmt := rstmt.GetStmt()
-	sel, ins, upd, del := stmt.GetSelectStmt(),
+	sel, ins, upd, del, merge := stmt.GetSelectStmt(),
 		stmt.GetInsertStmt(),
 		stmt.GetUpdateStmt(),
-		stmt.GetDeleteStmt()
+		stmt.GetDeleteStmt(),
+		stmt.GetMergeStmt()
 
 	switch {
 	case sel != nil:
-		action, err = parseSelectStmt(sel)
+		action, err = parseSelectStmt(sel, opts)
 	case ins != nil:
-		action, err = parseInsertStmt(ins)
+		action, err = parseInsertStmt(ins, opts)
 	case upd != nil:
-		action, err = parseUpdateStmt(upd)
+		action, err = parseUpdateStmt(upd, opts)
 	case del != nil:
-		action, err = parseDeleteStmt(del)
+		action, err = parseDeleteStmt(del, opts)
+	case merge != nil:
+		action, err = parseMergeStmt(merge, opts)
 	default:
-		// @TODO support UPSERT and MERGE
-		return nil, stmtErrorf(rstmt, "only support SELECT, INSERT, UPDATE or DELETE statements")
+		return nil, stmtErrorf(rstmt, "only support SELECT, INSERT, UPDATE, DELETE or MERGE statements")
+	}
+
+	if err != nil {
+		return nil, stmtErrorf(rstmt, "%w", err)
+	}
+
+	if err := checkAction(action, opts); err != nil {
+		return nil, stmtErrorf(rstmt, "%w", err)
+	}
+
+	if opts.DisallowStarAnywhere {
+		if err := checkNoStar(stmt); err != nil {
+			return nil, stmtErrorf(rstmt, "%w", err)
+		}
+	}
+
+	if opts.RequireQualifiedRelations {
+		if err := checkQualifiedRelations(stmt); err != nil {
+			return nil, stmtErrorf(rstmt, "%w", err)
+		}
 	}
 
+	info := action.info()
+	info.SQL = strings.TrimSpace(sql)
+	info.stmt, info.location = stmt, rstmt.GetStmtLocation()+int32(strings.Index(sql, info.SQL)) //nolint:gosec
+	meta, err := parseNameDirective(comments)
 	if err != nil {
 		return nil, stmtErrorf(rstmt, "%w", err)
 	}
 
-	// @TODO walk the stmt node tree to find AExpr nodes where the "name" == "@" and "rexpr" is not nil. Take care of
-	// differencees in the AST tree when using "CAST .. AS text" and "::text"
+	info.Name, info.Cardinality = meta.Name, meta.Cardinality
+	info.Comment = comment
+	info.Relations = parseRelations(stmt)
+	info.Warnings = append(info.Warnings, implicitCoercionWarnings(stmt)...)
 
-	if err := checkAction(action); err != nil {
+	for _, output := range action.getOutputs() {
+		output.scope = info.Name
+		if output.scope == "" {
+			output.scope = actionKind(action)
+		}
+
+		output.Doc = opts.Docs[output.StableID()]
+		if nullable, ok := opts.NullableOverrides[output.StableID()]; ok {
+			output.Nullable = nullable
+		}
+	}
+
+	if info.fingerprint, err = fingerprint(sql, action); err != nil {
 		return nil, stmtErrorf(rstmt, "%w", err)
 	}
 
+	for _, hook := range opts.ActionHooks {
+		if err := hook(action); err != nil {
+			return nil, stmtErrorf(rstmt, "hook: %w", err)
+		}
+	}
+
 	return action, nil
 }
 
@@ -261,31 +983,318 @@ func parseStmt(rstmt *pgquery.RawStmt) (action Action, err error) {
 // First, it requires all result columns and named arguments in the result to be explicitly typed via typecasts ("::").
 // Second, each column in the result set must also be aliased  using the "AS" operation. And finally, each alias and
 // named argument must be suffixed with a "_<N>", where N is a long-term fixed integer (>0) that should not change as
-// queries evolve over time.
-func ParseFullTyped(input []byte) (actions []Action, err error) {
+// queries evolve over time. Result columns are not nullable, unless the alias is quoted with a trailing "?" (e.g:
+// `AS "nick_2?"`) which marks the column as nullable. Each statement that can't be parsed yields a [ParseError], these
+// are joined into the returned error.
+func ParseFullTyped(input []byte, opts ...Option) (actions []Action, err error) {
+	options := newOptions(opts...)
+
 	result, err := pgquery.Parse(string(input))
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse: %w", err)
 	}
 
-	for _, rstmt := range result.GetStmts() {
-		action, perr := parseStmt(rstmt)
+	scan, err := pgquery.Scan(string(input))
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan: %w", err)
+	}
+
+	names := map[string]int32{}
+	for idx, rstmt := range result.GetStmts() {
+		action, perr := parseStmt(rstmt, stmtSQL(input, rstmt),
+			leadingComments(input, scan.GetTokens(), rstmt),
+			commentBlock(input, scan.GetTokens(), rstmt),
+			options)
+		if perr == nil {
+			perr = checkQueryName(action, names, input)
+		}
+
 		if perr != nil {
-			err = errors.Join(err, perr)
+			err = errors.Join(err, newParseError(idx, perr, input))
 		} else {
 			actions = append(actions, action)
 		}
 	}
 
+	if err == nil {
+		err = errors.Join(checkAmbiguousIDs("doc", slices.Collect(maps.Keys(options.Docs)), actions, input),
+			checkAmbiguousIDs("nullable override", slices.Collect(maps.Keys(options.NullableOverrides)), actions, input),
+			checkNullableOverrides(actions, options))
+	}
+
 	return actions, err
 }
 
-func stmtErrorf(rstmt *pgquery.RawStmt, format string, args ...any) error {
-	return fmt.Errorf("statement@%d: %w", rstmt.GetStmtLocation(), fmt.Errorf(format, args...))
+// ErrAmbiguousStableID is returned when an option is keyed by a [Output.StableID] that matches the outputs of more
+// than one statement, e.g: "select:1" when there is more than one unnamed select. Name the statements with a
+// "-- name:" comment to tell them apart.
+var ErrAmbiguousStableID = errors.New("ambiguous stable id")
+
+// checkAmbiguousIDs checks that each of the stable ids, that the option of the kind is keyed by, matches the outputs
+// of at most one of the actions.
+func checkAmbiguousIDs(kind string, ids []string, actions []Action, input []byte) (err error) {
+	locations := map[string][]int32{}
+	for _, action := range actions {
+		for _, output := range action.getOutputs() {
+			locations[output.StableID()] = append(locations[output.StableID()], action.info().location)
+		}
+	}
+
+	for _, id := range slices.Sorted(slices.Values(ids)) {
+		if len(locations[id]) < 2 {
+			continue
+		}
+
+		positions := lo.Map(locations[id], func(location int32, _ int) string {
+			line, column := linePosition(input, location)
+
+			return fmt.Sprintf("%d:%d", line, column)
+		})
+
+		err = errors.Join(err, fmt.Errorf("%w: %s for %s matches the statements at %s",
+			ErrAmbiguousStableID, kind, id, strings.Join(positions, ", ")))
+	}
+
+	return err
+}
+
+// ErrDuplicateQueryName is returned when more than one statement declares the same name with a "-- name:" comment.
+var ErrDuplicateQueryName = errors.New("duplicate query name")
+
+// checkQueryName checks that the name declared for the action isn't declared for another action, given the locations
+// of the names that are declared so far.
+func checkQueryName(action Action, names map[string]int32, input []byte) error {
+	info := action.info()
+	if info.Name == "" {
+		return nil
+	}
+
+	if other, exists := names[info.Name]; exists {
+		line, column := linePosition(input, other)
+
+		return positionErrorf("statement", info.location, "%w: %s, also declared by the statement at %d:%d",
+			ErrDuplicateQueryName, info.Name, line, column)
+	}
+
+	names[info.Name] = info.location
+
+	return nil
+}
+
+// ErrUnknownNullableOverride is returned when a nullable override doesn't match any output.
+var ErrUnknownNullableOverride = errors.New("nullable override for unknown output")
+
+// checkNullableOverrides checks that each nullable override matches the output of one of the actions. Overrides that
+// match the outputs of more than one action are rejected by checkAmbiguousIDs.
+func checkNullableOverrides(actions []Action, opts Options) (err error) {
+	ids := map[string]bool{}
+	for _, action := range actions {
+		for _, output := range action.getOutputs() {
+			ids[output.StableID()] = true
+		}
+	}
+
+	for _, id := range slices.Sorted(maps.Keys(opts.NullableOverrides)) {
+		if !ids[id] {
+			err = errors.Join(err, fmt.Errorf("%w: %s", ErrUnknownNullableOverride, id))
+		}
+	}
+
+	return err
+}
+
+// ParseReader reads all SQL from the reader and parses it, see [ParseFullTyped].
+func ParseReader(r io.Reader, opts ...Option) ([]Action, error) {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read: %w", err)
+	}
+
+	return ParseFullTyped(input, opts...)
+}
+
+// ParseFile reads the SQL file and parses it, see [ParseFullTyped]. The errors mention the file, e.g: "users.sql:
+// statement at 12:1: ...".
+func ParseFile(path string, opts ...Option) ([]Action, error) {
+	input, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read: %w", err)
+	}
+
+	actions, err := ParseFullTyped(input, opts...)
+
+	return actions, withFile(err, path)
+}
+
+// withFile makes each of the (joined) errors mention the file. Parse errors record it in [ParseError.File], other
+// errors are prefixed with it.
+func withFile(err error, path string) error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok { //nolint:errorlint
+		return errors.Join(lo.Map(joined.Unwrap(), func(jerr error, _ int) error { return withFile(jerr, path) })...)
+	}
+
+	if perr, ok := err.(*ParseError); ok { //nolint:errorlint
+		perr.File = path
+
+		return perr
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	return nil
+}
+
+// stmtSQL returns the SQL text of the statement. The last statement has no length if it isn't terminated by a
+// semicolon, it extends to the end of the input.
+func stmtSQL(input []byte, rstmt *pgquery.RawStmt) string {
+	start, length := int(rstmt.GetStmtLocation()), int(rstmt.GetStmtLen())
+	if length == 0 {
+		return string(input[start:])
+	}
+
+	return string(input[start : start+length])
+}
+
+// fingerprint the statement. Postgres' fingerprint ignores the aliases of result columns, but since these determine
+// the generated code they are included. Just like the name of the action.
+func fingerprint(sql string, action Action) (string, error) {
+	fprint, err := pgquery.FingerprintToUInt64(sql)
+	if err != nil {
+		return "", fmt.Errorf("failed to fingerprint: %w", err)
+	}
+
+	names := []string{action.info().Name}
+	if card := action.info().Cardinality; card != "" {
+		names[0] += " :" + string(card)
+	}
+
+	for _, input := range action.getInputs() {
+		names = append(names, input.Name)
+	}
+
+	for _, output := range action.getOutputs() {
+		names = append(names, output.Name)
+	}
+
+	return fmt.Sprintf("%016x", pgquery.HashXXH3_64([]byte(strings.Join(names, "\x00")), fprint)), nil
+}
+
+// leadingComments returns the text of the comments that precede the first token of the statement.
+func leadingComments(input []byte, tokens []*pgquery.ScanToken, rstmt *pgquery.RawStmt) (comments []string) {
+	for _, token := range tokens {
+		if token.GetStart() < rstmt.GetStmtLocation() {
+			continue
+		}
+
+		switch token.GetToken() { //nolint:exhaustive
+		case pgquery.Token_SQL_COMMENT, pgquery.Token_C_COMMENT:
+			comments = append(comments, string(input[token.GetStart():token.GetEnd()]))
+		default:
+			return comments
+		}
+	}
+
+	return comments
+}
+
+// commentBlock returns the text of the contiguous block of comments that immediately precedes the first token of the
+// statement. Comment markers are removed, as are lines with a directive like "-- name:".
+func commentBlock(input []byte, tokens []*pgquery.ScanToken, rstmt *pgquery.RawStmt) string {
+	var lines []string
+
+	prevEnd := int32(-1)
+	for _, token := range tokens {
+		if token.GetStart() < rstmt.GetStmtLocation() {
+			continue
+		}
+
+		if prevEnd >= 0 && strings.Count(string(input[prevEnd:token.GetStart()]), "\n") > 1 {
+			lines = nil // a blank line ends the block
+		}
+
+		switch token.GetToken() { //nolint:exhaustive
+		case pgquery.Token_SQL_COMMENT, pgquery.Token_C_COMMENT:
+			lines = append(lines, commentLines(string(input[token.GetStart():token.GetEnd()]))...)
+			prevEnd = token.GetEnd()
+		default:
+			return strings.TrimSpace(strings.Join(lines, "\n"))
+		}
+	}
+
+	return strings.TrimSpace(strings.Join(lines, "\n"))
 }
 
-func resTargetErrorf(rstmt *pgquery.ResTarget, format string, args ...any) error {
-	return fmt.Errorf("result_target@%d: %w", rstmt.GetLocation(), fmt.Errorf(format, args...))
+// commentLines returns the lines of text in a comment, without the comment markers. Directives yield no lines.
+func commentLines(comment string) (lines []string) {
+	if text, ok := strings.CutPrefix(comment, "--"); ok {
+		if nameDirective([]string{comment}) != "" {
+			return nil
+		}
+
+		return []string{strings.TrimSpace(text)}
+	}
+
+	text := strings.TrimSuffix(strings.TrimPrefix(comment, "/*"), "*/")
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		line = strings.TrimSpace(strings.TrimPrefix(line, "*"))
+		lines = append(lines, line)
+	}
+
+	return lines
+}
+
+// nameDirective returns the name declared in a "-- name: <Name>" comment.
+func nameDirective(comments []string) string {
+	meta, _ := parseNameDirective(comments)
+
+	return meta.Name
+}
+
+// ErrInvalidCardinality is returned when a "-- name:" comment declares an unknown cardinality.
+var ErrInvalidCardinality = errors.New(`invalid cardinality, must be one of ":one", ":many" or ":exec"`)
+
+// parseNameDirective parses the name and the optional cardinality that are declared in a "-- name: <Name> :<Card>"
+// comment, e.g: "-- name: GetUser :one".
+func parseNameDirective(comments []string) (meta QueryMeta, err error) {
+	for _, comment := range comments {
+		text, ok := strings.CutPrefix(comment, "--")
+		if !ok {
+			continue
+		}
+
+		text, ok = strings.CutPrefix(strings.TrimSpace(text), "name:")
+		if !ok {
+			continue
+		}
+
+		fields := strings.Fields(text)
+		if len(fields) == 0 {
+			continue
+		}
+
+		meta.Name = fields[0]
+		if len(fields) < 2 {
+			return meta, nil
+		}
+
+		switch card := Cardinality(strings.TrimPrefix(fields[1], ":")); card {
+		case CardinalityOne, CardinalityMany, CardinalityExec:
+			meta.Cardinality = card
+		default:
+			return meta, fmt.Errorf("%w: %s", ErrInvalidCardinality, fields[1])
+		}
+
+		return meta, nil
+	}
+
+	return meta, nil
+}
+
+func stmtErrorf(rstmt *pgquery.RawStmt, format string, args ...any) error {
+	return positionErrorf("statement", rstmt.GetStmtLocation(), format, args...)
 }
 
 func panicf(node *pgquery.Node, format string, args ...any) {
@@ -299,26 +1308,54 @@ func sdump(v any) string {
 // ErrNamedWithoutNumberSuffix is returned when a column name (alias) does not have a numbered suffix.
 var ErrNamedWithoutNumberSuffix = errors.New("not named with a number suffix, add _<N> at the end")
 
-// ErrInvalidNumberSuffix is returned when the name has a number suffix, but its invalid.
+// ErrInvalidNumberSuffix is returned when the name has a number suffix, but its invalid: zero or too large for an int.
 var ErrInvalidNumberSuffix = errors.New("invalid number suffix for name, must be > 0")
 
-// numberedName extracts the number at the end of a string separated by an underscores.
-func numberedName(name string) (int, error) {
-	lastUnderscore := strings.LastIndex(name, "_")
-	if lastUnderscore == -1 || lastUnderscore == len(name)-1 {
-		return 0, ErrNamedWithoutNumberSuffix
+// ErrInvalidBaseName is returned when the name without its number suffix can't be used as the name of a generated
+// field, e.g: "_1" or "1st_2".
+var ErrInvalidBaseName = errors.New("name must start with a letter")
+
+// validBaseName returns whether the name without its number suffix maps onto a valid field name in generated code.
+func validBaseName(base string) bool {
+	field := snakeCase(base)
+
+	return field != "" && (field[0] < '0' || field[0] > '9') // the snake case name only holds ASCII letters and digits
+}
+
+// defaultSuffixPattern matches the number suffix of names by default.
+var defaultSuffixPattern = regexp.MustCompile(`_(\d+)$`)
+
+// numberedName extracts the number at the end of a name, as captured by the first group of the suffix pattern. It
+// also returns the name without the number suffix.
+func numberedName(name string, pattern *regexp.Regexp) (string, int, error) {
+	if pattern == nil {
+		pattern = defaultSuffixPattern
 	}
 
-	numStr := name[lastUnderscore+1:]
+	match := pattern.FindStringSubmatchIndex(name)
+	if len(match) < 4 || match[2] < 0 {
+		return "", 0, ErrNamedWithoutNumberSuffix
+	}
 
-	num, err := strconv.Atoi(numStr)
-	if err != nil {
-		return 0, ErrNamedWithoutNumberSuffix
+	num, err := strconv.Atoi(name[match[2]:match[3]])
+	if errors.Is(err, strconv.ErrRange) {
+		return "", 0, ErrInvalidNumberSuffix
+	} else if err != nil {
+		return "", 0, ErrNamedWithoutNumberSuffix
 	}
 
 	if num < 1 {
-		return 0, ErrInvalidNumberSuffix
+		return "", 0, ErrInvalidNumberSuffix
 	}
 
-	return num, nil
+	return name[:match[0]], num, nil
+}
+
+// ErrReservedFieldNumber is returned when an output is numbered with a number that can't be used as a protobuf field
+// number: the range 19000-19999 is reserved for the protobuf implementation and 536870911 is the largest number.
+var ErrReservedFieldNumber = errors.New("number can't be used as protobuf field number")
+
+// isReservedFieldNumber returns whether the number can't be used as a protobuf field number.
+func isReservedFieldNumber(num int) bool {
+	return (num >= 19000 && num <= 19999) || num > 536870911
 }
@@ -3,9 +3,12 @@ package pgproto_test
 import (
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"regexp"
+	"strings"
 	"testing"
 
 	"github.com/crewlinker/pgproto"
@@ -15,8 +18,29 @@ import (
 //go:embed testdata/*
 var testdata embed.FS
 
+// golden compares the generated content with the golden file of that name in the testdata directory. The golden file
+// is written if it doesn't exist and PGPROTO_REFRESH_SNAPSHOT is set.
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+
+	exp, err := testdata.ReadFile(filepath.Join("testdata", name))
+	if os.IsNotExist(err) && os.Getenv("PGPROTO_REFRESH_SNAPSHOT") != "" {
+		fmt.Fprintf(os.Stderr, "refreshed golden file: %s", name)
+
+		require.NoError(t, os.WriteFile(filepath.Join("testdata", name), got, 0o777))
+		exp = got
+	} else {
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, string(exp), string(got))
+}
+
 func TestParse(t *testing.T) {
-	for _, tt := range []struct{ filename string }{
+	for _, tt := range []struct {
+		filename string
+		opts     []pgproto.Option
+	}{
 		{filename: "simple_select.sql"},
 		{filename: "simple_insert.sql"},
 		{filename: "simple_delete.sql"},
@@ -24,12 +48,52 @@ func TestParse(t *testing.T) {
 		{filename: "double_cast_select.sql"},
 		{filename: "multi_cast_insert.sql"},
 		{filename: "constructor_cast_select.sql"},
+		{filename: "composite_returning_insert.sql", opts: []pgproto.Option{pgproto.WithCompositeOutputs()}},
+		{filename: "ambiguous_join_select.sql"},
+		{filename: "operator_expr_select.sql"},
+		{filename: "set_default_update.sql"},
+		{filename: "exists_select.sql"},
+		{filename: "grouping_select.sql"},
+		{filename: "star_subquery_select.sql"},
+		{filename: "upsert_excluded.sql"},
+		{filename: "tsvector_select.sql"},
+		{filename: "limit_all_select.sql"},
+		{filename: "fetch_first_select.sql"},
+		{filename: "json_build_select.sql"},
+		{filename: "implicit_coercion_select.sql"},
+		{filename: "is_distinct_select.sql"},
+		{filename: "bytea_literal_select.sql"},
+		{filename: "format_func_select.sql"},
+		{filename: "current_of_update.sql"},
+		{filename: "ordinal_order_select.sql"},
+		{filename: "self_join_select.sql"},
+		{filename: "system_columns_select.sql"},
+		{filename: "percentile_select.sql"},
+		{filename: "array_agg_select.sql"},
+		{filename: "jsonpath_select.sql"},
+		{filename: "overriding_insert.sql"},
+		{filename: "concat_select.sql"},
+		{filename: "qualified_select.sql", opts: []pgproto.Option{pgproto.WithQualifiedRelations()}},
+		{filename: "merge_returning.sql"},
+		{filename: "width_bucket_select.sql"},
+		{filename: "extract_select.sql"},
+		{filename: "cast_function_select.sql"},
+		{filename: "xml_select.sql"},
+		{filename: "containment_select.sql"},
+		{filename: "count_subquery_select.sql"},
+		{filename: "window_select.sql"},
+		{filename: "union_select.sql"},
+		{filename: "cte_select.sql"},
+		{filename: "any_array_select.sql"},
+		{filename: "bool_agg_select.sql"},
+		{filename: "nullable_select.sql"},
+		{filename: "parenthesized_select.sql"},
 	} {
 		t.Run(tt.filename, func(t *testing.T) {
 			data, err := testdata.ReadFile(filepath.Join("testdata", tt.filename))
 			require.NoError(t, err)
 
-			actions, err := pgproto.ParseFullTyped(data)
+			actions, err := pgproto.ParseFullTyped(data, tt.opts...)
 			require.NoError(t, err)
 			require.Len(t, actions, 1)
 
@@ -81,6 +145,22 @@ func TestInvalidSuffix(t *testing.T) {
 	_, err := pgproto.ParseFullTyped([]byte(`SELECT id AS id_0 from foo`))
 	require.ErrorContains(t, err, "invalid number suffix")
 	require.ErrorIs(t, err, pgproto.ErrInvalidNumberSuffix)
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT id AS id_99999999999999999999 from foo`))
+	require.ErrorIs(t, err, pgproto.ErrInvalidNumberSuffix)
+}
+
+func TestInvalidBaseName(t *testing.T) {
+	for _, alias := range []string{`"_1"`, `"1st_2"`, `"?_1"`} {
+		_, err := pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS ` + alias + ` FROM foo`))
+		require.ErrorIs(t, err, pgproto.ErrInvalidBaseName, alias)
+	}
+
+	_, err := pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS "_1" FROM foo`))
+	require.ErrorContains(t, err, "result_target at 1:8: alias '_1': name must start with a letter")
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1 FROM foo WHERE id = @_1::uuid`))
+	require.ErrorIs(t, err, pgproto.ErrInvalidBaseName)
 }
 
 func TestNotNamedIfSuffixNotInt(t *testing.T) {
@@ -94,3 +174,823 @@ func TestDuplicateNumberSuffix(t *testing.T) {
 	require.ErrorContains(t, err, "duplicate number suffix")
 	require.ErrorIs(t, err, pgproto.ErrDuplicateNumberSuffix)
 }
+
+func TestStarNotAllowed(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "table_cmd_select.sql"))
+	require.NoError(t, err)
+
+	_, err = pgproto.ParseFullTyped(data)
+	require.ErrorIs(t, err, pgproto.ErrStarNotAllowed)
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT foo.* FROM foo`))
+	require.ErrorIs(t, err, pgproto.ErrStarNotAllowed)
+}
+
+func TestCompositeOutputNotEnabled(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "composite_returning_insert.sql"))
+	require.NoError(t, err)
+
+	_, err = pgproto.ParseFullTyped(data)
+	require.ErrorIs(t, err, pgproto.ErrCompositeOutput)
+}
+
+func TestAmbiguousColumnWarning(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "ambiguous_join_select.sql"))
+	require.NoError(t, err)
+
+	actions, err := pgproto.ParseFullTyped(data)
+	require.NoError(t, err)
+
+	sel := actions[0].(*pgproto.SelectAction)
+	require.Len(t, sel.Warnings, 1)
+	require.Equal(t, pgproto.WarnAmbiguousColumn, sel.Warnings[0].Code)
+	require.Contains(t, sel.Warnings[0].Message, "'id'")
+}
+
+func TestDomainCast(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT email::email AS email_1, nick::nick AS nick_2 FROM users`),
+		pgproto.WithDomains(map[string]pgproto.DomainInfo{
+			"email": {Type: pgproto.TypeRef{Name: "text"}, NotNull: true},
+			"nick":  {Type: pgproto.TypeRef{Name: "text"}},
+		}))
+	require.NoError(t, err)
+
+	outputs := actions[0].(*pgproto.SelectAction).Outputs
+	require.Equal(t, "text", outputs[0].Type.Name)
+	require.False(t, outputs[0].Nullable)
+	require.Equal(t, "text", outputs[1].Type.Name)
+	require.True(t, outputs[1].Nullable)
+}
+
+func TestCustomSuffixPattern(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT id::int4 AS "user_id#3" FROM users`),
+		pgproto.WithSuffixPattern(regexp.MustCompile(`#(\d+)$`)))
+	require.NoError(t, err)
+
+	output := actions[0].(*pgproto.SelectAction).Outputs[0]
+	require.Equal(t, 3, output.Number)
+	require.Equal(t, "user_id", output.BaseName)
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT id::int4 AS id_1 FROM users`),
+		pgproto.WithSuffixPattern(regexp.MustCompile(`#(\d+)$`)))
+	require.ErrorIs(t, err, pgproto.ErrNamedWithoutNumberSuffix)
+}
+
+func TestNeverNullBoolOutputs(t *testing.T) {
+	for _, filename := range []string{"exists_select.sql", "is_distinct_select.sql"} {
+		data, err := testdata.ReadFile(filepath.Join("testdata", filename))
+		require.NoError(t, err)
+
+		actions, err := pgproto.ParseFullTyped(data)
+		require.NoError(t, err)
+
+		for _, output := range actions[0].(*pgproto.SelectAction).Outputs {
+			if output.Type.Name != "bool" {
+				continue
+			}
+
+			require.False(t, output.Nullable, output.Name)
+		}
+	}
+}
+
+func TestContiguousInputNumbers(t *testing.T) {
+	sql := []byte(`SELECT id::uuid AS id_1 FROM users WHERE tenant = @tenant_1::uuid AND name = @name_3::text`)
+
+	_, err := pgproto.ParseFullTyped(sql)
+	require.NoError(t, err)
+
+	_, err = pgproto.ParseFullTyped(sql, pgproto.WithContiguousInputNumbers())
+	require.ErrorIs(t, err, pgproto.ErrNonContiguousNumbers)
+	require.ErrorContains(t, err, "inputs: numbers are not contiguous, missing: 2")
+}
+
+func TestContiguousNumbers(t *testing.T) {
+	sql := []byte(`SELECT id::uuid AS id_1, name::text AS name_3 FROM users`)
+
+	_, err := pgproto.ParseFullTyped(sql)
+	require.NoError(t, err)
+
+	_, err = pgproto.ParseFullTyped(sql, pgproto.WithContiguousNumbers())
+	require.ErrorIs(t, err, pgproto.ErrNonContiguousNumbers)
+	require.ErrorContains(t, err, "outputs: numbers are not contiguous, missing: 2")
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT name::text AS name_2, id::uuid AS id_1 FROM users`),
+		pgproto.WithContiguousNumbers())
+	require.NoError(t, err)
+}
+
+func TestDisallowStarAnywhere(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "star_subquery_select.sql"))
+	require.NoError(t, err)
+
+	_, err = pgproto.ParseFullTyped(data, pgproto.WithDisallowStarAnywhere())
+	require.ErrorIs(t, err, pgproto.ErrStarNotAllowed)
+	require.ErrorContains(t, err, "star at 5:9")
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT count(*)::int8 AS n_1 FROM foo`), pgproto.WithDisallowStarAnywhere())
+	require.NoError(t, err)
+}
+
+func TestUpsertExcludedIsNotAnInput(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "upsert_excluded.sql"))
+	require.NoError(t, err)
+
+	actions, err := pgproto.ParseFullTyped(data)
+	require.NoError(t, err)
+
+	ins := actions[0].(*pgproto.InsertAction)
+	require.Len(t, ins.Inputs, 2)
+	require.Equal(t, []string{"id_1", "x_2"}, []string{ins.Inputs[0].Name, ins.Inputs[1].Name})
+	require.Len(t, ins.Outputs, 1)
+	require.Equal(t, "x_1", ins.Outputs[0].Name)
+}
+
+func TestHasLimit(t *testing.T) {
+	for filename, exp := range map[string]bool{
+		"simple_select.sql":      false,
+		"limit_all_select.sql":   false,
+		"fetch_first_select.sql": true,
+	} {
+		data, err := testdata.ReadFile(filepath.Join("testdata", filename))
+		require.NoError(t, err)
+
+		actions, err := pgproto.ParseFullTyped(data)
+		require.NoError(t, err)
+		require.Equal(t, exp, actions[0].(*pgproto.SelectAction).HasLimit, filename)
+	}
+}
+
+func TestPositionalInputNumbering(t *testing.T) {
+	sql := []byte(`SELECT id::uuid AS id_1 FROM users WHERE tenant = $1::uuid AND name = $2::text OR tenant = $1::uuid`)
+
+	actions, err := pgproto.ParseFullTyped(sql)
+	require.NoError(t, err)
+	require.Empty(t, actions[0].(*pgproto.SelectAction).Inputs)
+
+	actions, err = pgproto.ParseFullTyped(sql, pgproto.WithPositionalInputNumbering())
+	require.NoError(t, err)
+
+	inputs := actions[0].(*pgproto.SelectAction).Inputs
+	require.Len(t, inputs, 2)
+	require.Equal(t, 1, inputs[0].Number)
+	require.Equal(t, "uuid", inputs[0].Type.Name)
+	require.Equal(t, 2, inputs[1].Number)
+	require.Equal(t, "text", inputs[1].Type.Name)
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1 FROM users WHERE tenant = $1`),
+		pgproto.WithPositionalInputNumbering())
+	require.ErrorIs(t, err, pgproto.ErrInputWithoutCast)
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1 FROM users WHERE tenant = @tenant::uuid`),
+		pgproto.WithPositionalInputNumbering())
+	require.ErrorIs(t, err, pgproto.ErrNamedWithoutNumberSuffix)
+
+	for _, sql := range []string{
+		`SELECT id::uuid AS id_1 FROM users WHERE tenant = $1::uuid AND name = @name_2::text`,
+		`SELECT id::uuid AS id_1 FROM users WHERE tenant = @tenant_1::uuid AND name = $1::text`,
+	} {
+		_, err = pgproto.ParseFullTyped([]byte(sql), pgproto.WithPositionalInputNumbering())
+		require.ErrorIs(t, err, pgproto.ErrMixedInputStyles, sql)
+	}
+
+	_, err = pgproto.ParseFullTyped([]byte(
+		`SELECT id::uuid AS id_1 FROM users WHERE tenant = $1::uuid AND name = @name_2::text`),
+		pgproto.WithPositionalInputNumbering())
+	require.ErrorContains(t, err, "input at 1:71: argument 'name_2': positional and named arguments can't be mixed")
+
+	for _, sql := range []string{
+		`SELECT id::uuid AS id_1 FROM users WHERE a = $1::uuid AND b = @b_1::text`,
+		`SELECT id::uuid AS id_1 FROM users WHERE a = @a_1::uuid AND b = $1`,
+	} {
+		_, err = pgproto.ParseFullTyped([]byte(sql))
+		require.ErrorIs(t, err, pgproto.ErrMixedInputStyles, sql)
+	}
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1 FROM users WHERE a = $1::uuid AND b = @b_1::text`))
+	require.ErrorContains(t, err, "input at 1:46: argument $1: positional and named arguments can't be mixed")
+}
+
+func TestImplicitCoercionWarning(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "implicit_coercion_select.sql"))
+	require.NoError(t, err)
+
+	actions, err := pgproto.ParseFullTyped(data)
+	require.NoError(t, err)
+
+	sel := actions[0].(*pgproto.SelectAction)
+	require.Len(t, sel.Warnings, 1)
+	require.Equal(t, pgproto.WarnImplicitCoercion, sel.Warnings[0].Code)
+	require.Equal(t, "column 'e.created_at' is compared to literal '2020-01-01' without a type cast",
+		sel.Warnings[0].Message)
+}
+
+func TestActionHooks(t *testing.T) {
+	deprecateCreatedAt := func(action pgproto.Action) error {
+		sel, ok := action.(*pgproto.SelectAction)
+		if !ok {
+			return nil
+		}
+
+		for _, output := range sel.Outputs {
+			output.Deprecated = output.BaseName == "created_at"
+		}
+
+		return nil
+	}
+
+	actions, err := pgproto.ParseFullTyped(
+		[]byte(`SELECT id::uuid AS id_1, created_at::timestamptz AS created_at_2 FROM foo`),
+		pgproto.WithActionHooks(deprecateCreatedAt))
+	require.NoError(t, err)
+
+	outputs := actions[0].(*pgproto.SelectAction).Outputs
+	require.False(t, outputs[0].Deprecated)
+	require.True(t, outputs[1].Deprecated)
+
+	field, err := outputs[1].ProtoField(pgproto.ProtoOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "google.protobuf.Timestamp created_at = 2 [deprecated = true];", field)
+
+	errHook := errors.New("hook failed")
+	actions, err = pgproto.ParseFullTyped([]byte(`SELECT 1::int4 AS one_1; SELECT 2::int4 AS two_1`),
+		pgproto.WithActionHooks(func(action pgproto.Action) error {
+			if action.(*pgproto.SelectAction).Outputs[0].Name == "one_1" {
+				return errHook
+			}
+
+			return nil
+		}))
+	require.ErrorIs(t, err, errHook)
+	require.Len(t, actions, 1)
+}
+
+func TestByteaLiteral(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "bytea_literal_select.sql"))
+	require.NoError(t, err)
+
+	actions, err := pgproto.ParseFullTyped(data)
+	require.NoError(t, err)
+
+	for _, output := range actions[0].(*pgproto.SelectAction).Outputs {
+		require.False(t, output.Nullable)
+
+		field, err := output.ProtoField(pgproto.ProtoOptions{})
+		require.NoError(t, err)
+		require.Contains(t, field, "bytes ")
+
+		goType, err := pgproto.GoType(output.Type)
+		require.NoError(t, err)
+		require.Equal(t, "[]byte", goType)
+	}
+}
+
+func TestUpdateWhereCurrentOf(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "current_of_update.sql"))
+	require.NoError(t, err)
+
+	actions, err := pgproto.ParseFullTyped(data)
+	require.NoError(t, err)
+
+	upd := actions[0].(*pgproto.UpdateAction)
+	require.True(t, upd.UsesCursor)
+	require.Len(t, upd.Inputs, 1)
+	require.Equal(t, "x_1", upd.Inputs[0].Name)
+}
+
+func TestOrdinalReferencesAreNotOutputs(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "ordinal_order_select.sql"))
+	require.NoError(t, err)
+
+	actions, err := pgproto.ParseFullTyped(data)
+	require.NoError(t, err)
+
+	sel := actions[0].(*pgproto.SelectAction)
+	require.Empty(t, sel.Inputs)
+	require.Len(t, sel.Outputs, 2)
+	require.Equal(t, "name_1", sel.Outputs[0].Name)
+	require.Equal(t, "total_2", sel.Outputs[1].Name)
+	require.Empty(t, pgproto.Literals(sel))
+}
+
+func TestSelfJoin(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "self_join_select.sql"))
+	require.NoError(t, err)
+
+	actions, err := pgproto.ParseFullTyped(data)
+	require.NoError(t, err)
+
+	sel := actions[0].(*pgproto.SelectAction)
+	require.True(t, sel.SelfJoin)
+	require.Len(t, sel.Relations, 1)
+	require.Equal(t, []string{"a1", "a2"}, sel.Relations[0].Aliases)
+
+	actions, err = pgproto.ParseFullTyped([]byte(`SELECT id::int4 AS id_1 FROM t WHERE EXISTS (SELECT FROM t t2)`))
+	require.NoError(t, err)
+	require.False(t, actions[0].(*pgproto.SelectAction).SelfJoin)
+}
+
+func TestOrderedSetAggregate(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "percentile_select.sql"))
+	require.NoError(t, err)
+
+	actions, err := pgproto.ParseFullTyped(data)
+	require.NoError(t, err)
+
+	outputs := actions[0].(*pgproto.SelectAction).Outputs
+	require.True(t, outputs[0].Aggregate)
+	require.Equal(t, "float8", outputs[0].Type.Name)
+	require.True(t, outputs[1].Aggregate)
+	require.False(t, outputs[2].Aggregate)
+}
+
+func TestStatementComment(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`-- file header, not part of the block
+
+-- name: GetUser
+-- GetUser fetches a user
+-- by its ID.
+SELECT id::int4 AS id_1 FROM users WHERE id = @id_1::int4;
+
+/*
+ * ListUsers lists
+ * all users.
+ */
+SELECT id::int4 AS id_1 FROM users;
+SELECT id::int4 AS id_1 FROM teams;`))
+	require.NoError(t, err)
+	require.Len(t, actions, 3)
+
+	require.Equal(t, "GetUser fetches a user\nby its ID.", actions[0].(*pgproto.SelectAction).Comment)
+	require.Equal(t, "ListUsers lists\nall users.", actions[1].(*pgproto.SelectAction).Comment)
+	require.Empty(t, actions[2].(*pgproto.SelectAction).Comment)
+}
+
+func TestInsertOverriding(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "overriding_insert.sql"))
+	require.NoError(t, err)
+
+	actions, err := pgproto.ParseFullTyped(data)
+	require.NoError(t, err)
+	require.Equal(t, "SYSTEM", actions[0].(*pgproto.InsertAction).Overriding)
+
+	actions, err = pgproto.ParseFullTyped([]byte(`
+		INSERT INTO foo (id) OVERRIDING USER VALUE VALUES (@id_1::int4);
+		INSERT INTO foo (id) VALUES (@id_1::int4);`))
+	require.NoError(t, err)
+	require.Equal(t, "USER", actions[0].(*pgproto.InsertAction).Overriding)
+	require.Empty(t, actions[1].(*pgproto.InsertAction).Overriding)
+}
+
+func TestConcatWithoutCast(t *testing.T) {
+	_, err := pgproto.ParseFullTyped([]byte(`SELECT first || ' ' || last AS full_1 FROM people`))
+	require.ErrorIs(t, err, pgproto.ErrColumnWithoutCast)
+	require.ErrorContains(t, err, "alias 'full_1'")
+}
+
+func TestRequireQualifiedRelations(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "unqualified_select.sql"))
+	require.NoError(t, err)
+
+	_, err = pgproto.ParseFullTyped(data)
+	require.NoError(t, err)
+
+	_, err = pgproto.ParseFullTyped(data, pgproto.WithQualifiedRelations())
+	require.ErrorIs(t, err, pgproto.ErrRelationNotQualified)
+	require.ErrorContains(t, err, "relation at 5:10: relation not qualified with a schema: teams")
+}
+
+func TestErrorPosition(t *testing.T) {
+	_, err := pgproto.ParseFullTyped([]byte(
+		"SELECT id::int4 AS id_1 FROM foo;\n\nSELECT\n    bär::text,\n    id FROM foo;"))
+	require.ErrorContains(t, err, "statement at 3:1: result_target at 4:5: column 'bär': no alias")
+
+	var perr *pgproto.PositionError
+	require.ErrorAs(t, err, &perr)
+	require.Equal(t, "statement", perr.What)
+	require.Equal(t, int32(33), perr.Offset)
+	require.Equal(t, 3, perr.Line)
+	require.Equal(t, 1, perr.Column)
+
+	require.ErrorAs(t, perr.Err, &perr)
+	require.Equal(t, "result_target", perr.What)
+	require.Equal(t, int32(46), perr.Offset)
+	require.Equal(t, 4, perr.Line)
+	require.Equal(t, 5, perr.Column)
+}
+
+func TestUpsertOnConflict(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`
+		INSERT INTO users (email) VALUES (@email_1::text)
+		ON CONFLICT (email) DO UPDATE SET seen = now()
+		RETURNING id::uuid AS id_1;
+		INSERT INTO users (email) VALUES (@email_1::text) ON CONFLICT ON CONSTRAINT users_email_key DO NOTHING;
+		INSERT INTO users (email) VALUES (@email_1::text);`))
+	require.NoError(t, err)
+
+	upsert := actions[0].(*pgproto.InsertAction)
+	require.Equal(t, "id_1", upsert.Outputs[0].Name)
+	require.Equal(t, &pgproto.OnConflictInfo{
+		Action: "UPDATE", Columns: []string{"email"}, SetColumns: []string{"seen"},
+	}, upsert.OnConflict)
+	require.Equal(t, &pgproto.OnConflictInfo{
+		Action: "NOTHING", Constraint: "users_email_key",
+	}, actions[1].(*pgproto.InsertAction).OnConflict)
+	require.Nil(t, actions[2].(*pgproto.InsertAction).OnConflict)
+
+	clone := pgproto.CloneAction(upsert).(*pgproto.InsertAction)
+	clone.OnConflict.Columns[0] = "other"
+	require.Equal(t, "email", upsert.OnConflict.Columns[0])
+}
+
+func TestMergeReturning(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "merge_returning.sql"))
+	require.NoError(t, err)
+
+	actions, err := pgproto.ParseFullTyped(data)
+	require.NoError(t, err)
+
+	merge := actions[0].(*pgproto.MergeAction)
+	require.Len(t, merge.Outputs, 3)
+	require.Equal(t, "delivered_at_3", merge.Outputs[2].Name)
+	require.Equal(t, "MergeStock", pgproto.MethodName(merge))
+
+	_, err = pgproto.ParseFullTyped([]byte(`MERGE INTO stock t USING deliveries s ON t.item_id = s.item_id
+		WHEN MATCHED THEN DELETE RETURNING s.item_id AS item_id_1`))
+	require.ErrorIs(t, err, pgproto.ErrColumnWithoutCast)
+}
+
+func TestInferredAliases(t *testing.T) {
+	input := []byte(`SELECT u.id::uuid, name::text AS name_2 FROM users u`)
+
+	_, err := pgproto.ParseFullTyped(input)
+	require.ErrorIs(t, err, pgproto.ErrNoColumnAliasUsed)
+
+	actions, err := pgproto.ParseFullTyped(input, pgproto.WithInferredAliases())
+	require.NoError(t, err)
+
+	outputs := actions[0].(*pgproto.SelectAction).Outputs
+	require.Equal(t, "id", outputs[0].Name)
+	require.Equal(t, "id", outputs[0].BaseName)
+	require.Equal(t, 1, outputs[0].Number)
+	require.Equal(t, "name_2", outputs[1].Name)
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT (a+b)::int4 FROM t`), pgproto.WithInferredAliases())
+	require.ErrorIs(t, err, pgproto.ErrNoColumnAliasUsed)
+}
+
+func TestSuffixSeparator(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped(
+		[]byte(`SELECT id::uuid AS user_id__1 FROM users WHERE org = @org_id__1::uuid`),
+		pgproto.WithSuffixSeparator("__"))
+	require.NoError(t, err)
+
+	sel := actions[0].(*pgproto.SelectAction)
+	require.Equal(t, "user_id", sel.Outputs[0].BaseName)
+	require.Equal(t, 1, sel.Outputs[0].Number)
+	require.Equal(t, "org_id", sel.Inputs[0].BaseName)
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS user_id_1 FROM users`), pgproto.WithSuffixSeparator("__"))
+	require.ErrorIs(t, err, pgproto.ErrNamedWithoutNumberSuffix)
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS "id.0" FROM users`), pgproto.WithSuffixSeparator("."))
+	require.ErrorIs(t, err, pgproto.ErrInvalidNumberSuffix)
+
+	require.PanicsWithValue(t, "pgproto: suffix separator must not be empty", func() {
+		pgproto.WithSuffixSeparator("")
+	})
+}
+
+func TestNullableAggregates(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "bool_agg_select.sql"))
+	require.NoError(t, err)
+
+	actions, err := pgproto.ParseFullTyped(data)
+	require.NoError(t, err)
+
+	outputs := actions[0].(*pgproto.SelectAction).Outputs
+	require.False(t, outputs[0].Nullable, outputs[0].Name)
+	require.True(t, outputs[1].Aggregate)
+	require.True(t, outputs[1].Nullable, outputs[1].Name)
+	require.Equal(t, "bool", outputs[1].Type.Name)
+	require.True(t, outputs[2].Nullable, outputs[2].Name)
+	require.True(t, outputs[3].Aggregate)
+	require.False(t, outputs[3].Nullable, outputs[3].Name)
+}
+
+func TestNullableAnnotation(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "nullable_select.sql"))
+	require.NoError(t, err)
+
+	actions, err := pgproto.ParseFullTyped(data)
+	require.NoError(t, err)
+
+	outputs := actions[0].(*pgproto.SelectAction).Outputs
+	require.False(t, outputs[0].Nullable)
+	require.True(t, outputs[1].Nullable)
+	require.Equal(t, "nick_2", outputs[1].Name)
+	require.Equal(t, "nick", outputs[1].BaseName)
+
+	out, err := pgproto.GenerateProto(actions, pgproto.ProtoOptions{})
+	require.NoError(t, err)
+	require.Contains(t, string(out), "  string id = 1;\n  optional string nick = 2;\n  repeated string tags = 3;\n")
+}
+
+func TestArrayOutput(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT ARRAY['a','b']::text[] AS tags_1, m::int4[][] AS matrix_2`))
+	require.NoError(t, err)
+
+	outputs := actions[0].(*pgproto.SelectAction).Outputs
+	require.True(t, outputs[0].IsArray)
+	require.Equal(t, 1, outputs[0].ArrayDims)
+	require.Equal(t, "text", outputs[0].Type.Name)
+	require.True(t, outputs[1].IsArray)
+	require.Equal(t, 2, outputs[1].ArrayDims)
+}
+
+func TestRedundantParentheses(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "parenthesized_select.sql"))
+	require.NoError(t, err)
+
+	actions, err := pgproto.ParseFullTyped(data)
+	require.NoError(t, err)
+
+	sel := actions[0].(*pgproto.SelectAction)
+	require.Len(t, sel.Outputs, 3)
+	require.Equal(t, "int4", sel.Outputs[0].Type.Name)
+	require.Equal(t, "text", sel.Outputs[1].Type.Name)
+	require.Equal(t, "id_1", sel.Inputs[0].Name)
+
+	actions, err = pgproto.ParseFullTyped([]byte(`SELECT ((id))::int4 FROM foo`), pgproto.WithInferredAliases())
+	require.NoError(t, err)
+	require.Equal(t, "id", actions[0].(*pgproto.SelectAction).Outputs[0].Name)
+}
+
+func TestErrorsMentionSourceColumn(t *testing.T) {
+	_, err := pgproto.ParseFullTyped([]byte(`SELECT id FROM foo`))
+	require.ErrorIs(t, err, pgproto.ErrNoColumnAliasUsed)
+	require.ErrorContains(t, err, "result_target at 1:8: column 'id': no alias")
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT CAST(CAST(e.salary AS numeric(10, 2)) AS TEXT) FROM employees e`))
+	require.ErrorIs(t, err, pgproto.ErrNoColumnAliasUsed)
+	require.ErrorContains(t, err, "column 'e.salary'")
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT a + b AS total_1 FROM foo`))
+	require.ErrorIs(t, err, pgproto.ErrColumnWithoutCast)
+	require.ErrorContains(t, err, "alias 'total_1': column 'a': no type cast")
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT now() FROM foo`))
+	require.ErrorContains(t, err, "result_target at 1:8: no alias")
+}
+
+func TestParseError(t *testing.T) {
+	_, err := pgproto.ParseFullTyped([]byte(
+		"SELECT id::int4 AS id_1 FROM foo;\nSELECT name AS name_1 FROM foo;\nDROP TABLE foo;"))
+	require.ErrorIs(t, err, pgproto.ErrColumnWithoutCast)
+
+	joined, ok := err.(interface{ Unwrap() []error })
+	require.True(t, ok)
+	require.Len(t, joined.Unwrap(), 2)
+
+	var perr *pgproto.ParseError
+	require.ErrorAs(t, joined.Unwrap()[0], &perr)
+	require.Equal(t, 1, perr.Statement)
+	require.Equal(t, int32(41), perr.Offset)
+	require.Equal(t, 2, perr.Line)
+	require.Equal(t, 8, perr.Column)
+	require.Equal(t, "name_1", perr.Alias)
+	require.Equal(t, "name", perr.SourceColumn)
+	require.ErrorIs(t, perr, pgproto.ErrColumnWithoutCast)
+
+	require.ErrorAs(t, joined.Unwrap()[1], &perr)
+	require.Equal(t, 2, perr.Statement)
+	require.Equal(t, 3, perr.Line)
+	require.Equal(t, 1, perr.Column)
+	require.Empty(t, perr.Alias)
+}
+
+func TestCastFunctionEquivalent(t *testing.T) {
+	operator, err := pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1, (price::numeric(10, 2))::text AS price_2,
+		tags::text[] AS tags_3, coalesce(n, 0)::int4 AS n_4 FROM products WHERE owner_id = @owner_1::uuid`))
+	require.NoError(t, err)
+
+	function, err := pgproto.ParseFullTyped([]byte(`SELECT CAST(id AS uuid) AS id_1,
+		CAST(CAST(price AS numeric(10, 2)) AS text) AS price_2, CAST(tags AS text[]) AS tags_3,
+		CAST(coalesce(n, 0) AS int4) AS n_4 FROM products WHERE owner_id = CAST(@owner_1 AS uuid)`))
+	require.NoError(t, err)
+
+	opSel, fnSel := operator[0].(*pgproto.SelectAction), function[0].(*pgproto.SelectAction)
+	require.Equal(t, opSel.Outputs, fnSel.Outputs)
+	require.Equal(t, opSel.Inputs[0].Type, fnSel.Inputs[0].Type)
+	require.True(t, pgproto.ShapeEqual(operator[0], function[0]))
+}
+
+func TestTypeCastInvalidNamesParts(t *testing.T) {
+	_, err := pgproto.ParseFullTyped([]byte(`SELECT id::mydb.public.my_type AS id_1 FROM foo`))
+	require.ErrorIs(t, err, pgproto.ErrTypeCastInvalid)
+	require.ErrorContains(t, err, "number of parts: 3: mydb.public.my_type")
+}
+
+func TestParseFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "users.sql")
+	require.NoError(t, os.WriteFile(path, []byte("SELECT id::uuid AS id_1 FROM users;\nSELECT name FROM users;"), 0o600))
+
+	actions, err := pgproto.ParseFile(path)
+	require.ErrorIs(t, err, pgproto.ErrNoColumnAliasUsed)
+	require.ErrorContains(t, err, path+": statement at 2:1: result_target at 2:8")
+	require.Len(t, actions, 1)
+
+	var perr *pgproto.ParseError
+	require.ErrorAs(t, err, &perr)
+	require.Equal(t, path, perr.File)
+
+	_, err = pgproto.ParseFile(filepath.Join(dir, "missing.sql"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	path = filepath.Join(dir, "names.sql")
+	require.NoError(t, os.WriteFile(path, []byte("SELECT name::text AS name_1 FROM users;"), 0o600))
+
+	_, err = pgproto.ParseFile(path, pgproto.WithNullableOverrides(map[string]bool{"select:2": true, "select:3": true}))
+	require.ErrorIs(t, err, pgproto.ErrUnknownNullableOverride)
+	require.EqualError(t, err, path+": nullable override for unknown output: select:2\n"+
+		path+": nullable override for unknown output: select:3")
+}
+
+func TestParseReader(t *testing.T) {
+	actions, err := pgproto.ParseReader(strings.NewReader(`SELECT id::uuid AS id_1 FROM users`))
+	require.NoError(t, err)
+	require.Len(t, actions, 1)
+
+	_, err = pgproto.ParseReader(strings.NewReader(`SELECT id FROM users`))
+	require.ErrorIs(t, err, pgproto.ErrNoColumnAliasUsed)
+}
+
+func TestActionSQL(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1 FROM users;
+
+-- name: UserNames
+SELECT name::text AS name_1 FROM users ;
+DELETE FROM users WHERE id = @id_1::uuid
+`))
+	require.NoError(t, err)
+	require.Len(t, actions, 3)
+	require.Equal(t, "SELECT id::uuid AS id_1 FROM users", actions[0].(*pgproto.SelectAction).SQL)
+	require.Equal(t, "-- name: UserNames\nSELECT name::text AS name_1 FROM users", actions[1].(*pgproto.SelectAction).SQL)
+	require.Equal(t, "DELETE FROM users WHERE id = @id_1::uuid", actions[2].(*pgproto.DeleteAction).SQL)
+}
+
+func TestWindowFunctionOutputs(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT row_number() OVER (ORDER BY created)::int8 AS rn_1 FROM events`))
+	require.NoError(t, err)
+
+	output := actions[0].(*pgproto.SelectAction).Outputs[0]
+	require.Equal(t, "int8", output.Type.Name)
+	require.False(t, output.Aggregate)
+	require.False(t, output.Nullable)
+	require.True(t, pgproto.ConnectMethods(actions)[0].ServerStreaming)
+}
+
+func TestNullableOverrides(t *testing.T) {
+	sql := []byte(`SELECT id::uuid AS id_1, max(age)::int4 AS age_2, nick::text AS nick_3 FROM users;
+-- name: Names
+SELECT name::text AS name_1 FROM users;`)
+
+	actions, err := pgproto.ParseFullTyped(sql, pgproto.WithNullableOverrides(map[string]bool{
+		"select:2": false, "select:3": true, "Names:1": true,
+	}))
+	require.NoError(t, err)
+
+	outputs := actions[0].(*pgproto.SelectAction).Outputs
+	require.False(t, outputs[0].Nullable)
+	require.False(t, outputs[1].Nullable)
+	require.True(t, outputs[2].Nullable)
+	require.True(t, actions[1].(*pgproto.SelectAction).Outputs[0].Nullable)
+
+	_, err = pgproto.ParseFullTyped(sql, pgproto.WithNullableOverrides(map[string]bool{"select:4": true, "Names:1": true}))
+	require.ErrorIs(t, err, pgproto.ErrUnknownNullableOverride)
+	require.EqualError(t, err, "nullable override for unknown output: select:4")
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1 FROM users; SELECT id::uuid AS id_1 FROM orders;`),
+		pgproto.WithNullableOverrides(map[string]bool{"select:1": true}))
+	require.ErrorIs(t, err, pgproto.ErrAmbiguousStableID)
+	require.EqualError(t, err,
+		"ambiguous stable id: nullable override for select:1 matches the statements at 1:1, 1:37")
+}
+
+func TestAmbiguousDocs(t *testing.T) {
+	sql := []byte(`SELECT id::uuid AS id_1 FROM users;
+SELECT id::uuid AS id_1 FROM orders;
+-- name: Names
+SELECT name::text AS name_1 FROM users;`)
+
+	_, err := pgproto.ParseFullTyped(sql, pgproto.WithDocs(map[string]string{"Names:1": "Name of the user."}))
+	require.NoError(t, err)
+
+	_, err = pgproto.ParseFullTyped(sql, pgproto.WithDocs(map[string]string{"select:1": "ID."}))
+	require.ErrorIs(t, err, pgproto.ErrAmbiguousStableID)
+	require.EqualError(t, err, "ambiguous stable id: doc for select:1 matches the statements at 1:1, 2:1")
+}
+
+func TestDistinctOutputs(t *testing.T) {
+	plain, err := pgproto.ParseFullTyped([]byte(
+		`SELECT id::uuid AS id_1, user_id::uuid AS user_id_2 FROM events ORDER BY user_id, created DESC`))
+	require.NoError(t, err)
+
+	for _, sql := range []string{
+		`SELECT DISTINCT id::uuid AS id_1, user_id::uuid AS user_id_2 FROM events ORDER BY user_id, created DESC`,
+		`SELECT DISTINCT ON (user_id) id::uuid AS id_1, user_id::uuid AS user_id_2 FROM events
+			ORDER BY user_id, created DESC`,
+		`SELECT DISTINCT ON (user_id, lower(kind)) id::uuid AS id_1, user_id::uuid AS user_id_2 FROM events
+			ORDER BY user_id, created DESC`,
+	} {
+		distinct, err := pgproto.ParseFullTyped([]byte(sql))
+		require.NoError(t, err)
+		require.Equal(t, plain[0].(*pgproto.SelectAction).Outputs, distinct[0].(*pgproto.SelectAction).Outputs)
+	}
+}
+
+func TestSetOperationMismatch(t *testing.T) {
+	_, err := pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1 FROM a UNION SELECT id::uuid AS key_1 FROM b`))
+	require.ErrorIs(t, err, pgproto.ErrSetOperationMismatch)
+	require.ErrorContains(t, err, "set operation arm 2: arms of set operation have different outputs: "+
+		"output 'key_1' of type uuid does not match 'id_1' of type uuid")
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1 FROM a INTERSECT SELECT id::text AS id_1 FROM b`))
+	require.ErrorIs(t, err, pgproto.ErrSetOperationMismatch)
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1 FROM a
+		UNION ALL SELECT id::uuid AS id_1, name::text AS name_2 FROM b`))
+	require.ErrorIs(t, err, pgproto.ErrSetOperationMismatch)
+	require.ErrorContains(t, err, "2 outputs instead of 1")
+
+	_, err = pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1 FROM a UNION SELECT id FROM b`))
+	require.ErrorIs(t, err, pgproto.ErrNoColumnAliasUsed)
+}
+
+func TestCommonTableExpressions(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(
+		`WITH del AS (DELETE FROM stale RETURNING id::uuid AS id_1) SELECT id::uuid AS id_1 FROM del`))
+	require.NoError(t, err)
+
+	sel := actions[0].(*pgproto.SelectAction)
+	require.Len(t, sel.Outputs, 1)
+	require.Equal(t, "id_1", sel.Outputs[0].Name)
+	require.Len(t, sel.Relations, 1)
+	require.Equal(t, "stale", sel.Relations[0].Name)
+}
+
+func TestReservedFieldNumber(t *testing.T) {
+	for _, alias := range []string{"x_19000", "x_19500", "x_19999", "x_536870912"} {
+		_, err := pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1, x::text AS ` + alias + ` FROM users`))
+		require.ErrorIs(t, err, pgproto.ErrReservedFieldNumber, alias)
+		require.ErrorContains(t, err, "result_target at 1:26: alias '"+alias+"'")
+	}
+
+	for _, alias := range []string{"x_18999", "x_20000", "x_536870911"} {
+		_, err := pgproto.ParseFullTyped([]byte(`SELECT x::text AS ` + alias + ` FROM users`))
+		require.NoError(t, err, alias)
+	}
+}
+
+func TestQueryMeta(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`-- name: GetUser :one
+SELECT id::uuid AS id_1 FROM users WHERE id = @id_1::uuid;
+
+-- name: ListUsers :many
+SELECT id::uuid AS id_1 FROM users;
+
+-- name: DeleteUser :exec
+DELETE FROM users WHERE id = @id_1::uuid;
+
+-- name: CountUsers
+SELECT count(*)::int8 AS n_1 FROM users;
+
+SELECT name::text AS name_1 FROM users;`))
+	require.NoError(t, err)
+	require.Equal(t, pgproto.QueryMeta{Name: "GetUser", Cardinality: pgproto.CardinalityOne}, actions[0].QueryMeta())
+	require.Equal(t, pgproto.QueryMeta{Name: "ListUsers", Cardinality: pgproto.CardinalityMany}, actions[1].QueryMeta())
+	require.Equal(t, pgproto.QueryMeta{Name: "DeleteUser", Cardinality: pgproto.CardinalityExec}, actions[2].QueryMeta())
+	require.Equal(t, pgproto.QueryMeta{Name: "CountUsers"}, actions[3].QueryMeta())
+	require.Equal(t, pgproto.QueryMeta{}, actions[4].QueryMeta())
+
+	require.False(t, pgproto.ConnectMethods(actions)[0].ServerStreaming)
+	require.True(t, pgproto.ConnectMethods(actions)[1].ServerStreaming)
+	require.False(t, pgproto.ConnectMethods(actions)[2].ServerStreaming)
+	require.False(t, pgproto.ConnectMethods(actions)[3].ServerStreaming)
+	require.True(t, pgproto.ConnectMethods(actions)[4].ServerStreaming)
+}
+
+func TestQueryMetaErrors(t *testing.T) {
+	_, err := pgproto.ParseFullTyped([]byte("-- name: GetUser :some\nSELECT id::uuid AS id_1 FROM users"))
+	require.ErrorIs(t, err, pgproto.ErrInvalidCardinality)
+	require.ErrorContains(t, err, ":some")
+
+	actions, err := pgproto.ParseFullTyped([]byte(`-- name: GetUser :one
+SELECT id::uuid AS id_1 FROM users LIMIT 1;
+-- name: GetUser :many
+SELECT name::text AS name_1 FROM users;`))
+	require.ErrorIs(t, err, pgproto.ErrDuplicateQueryName)
+	require.EqualError(t, err, "statement at 3:1: duplicate query name: GetUser, also declared by the statement at 1:1")
+	require.Len(t, actions, 1)
+}
@@ -0,0 +1,61 @@
+package pgproto
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+)
+
+// GeneratePgxScanners generates a Go file that declares a function for each action with outputs, that scans a
+// [pgx.Row] into the response struct generated by [GenerateGoStructs], e.g: "ScanSelectUsers(row pgx.Row)
+// (SelectUsersResponse, error)". The file is meant to be generated into the same package as the structs.
+//
+// Postgres returns the columns in the order of the result set, so the outputs are scanned in that order rather than
+// in the order of their number. The numbers only identify the fields of the protobuf messages. Arrays are scanned
+// into slices, nullable outputs into pointers.
+//
+// [pgx.Row]: https://pkg.go.dev/github.com/jackc/pgx/v5#Row
+func GeneratePgxScanners(actions []Action, opts GoOptions) ([]byte, error) {
+	var body bytes.Buffer
+	for idx, name := range MethodNames(actions) {
+		outputs := actions[idx].getOutputs()
+		if len(outputs) == 0 {
+			continue
+		}
+
+		if err := checkGoFieldNames(outputs); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		fmt.Fprintf(&body, "\n// Scan%s scans a row of %s into a %sResponse.\n", name, name, name)
+		fmt.Fprintf(&body, "func Scan%s(row pgx.Row) (%sResponse, error) {\n", name, name)
+		fmt.Fprintf(&body, "\tvar res %sResponse\n\n\terr := row.Scan(", name)
+
+		for i, output := range outputs {
+			if i > 0 {
+				body.WriteString(", ")
+			}
+
+			fmt.Fprintf(&body, "&res.%s", camelCase(output.BaseName))
+		}
+
+		body.WriteString(")\n\n\treturn res, err\n}\n")
+	}
+
+	var buf bytes.Buffer
+	writeBanner(&buf, "//", opts.GeneratorName)
+	fmt.Fprintf(&buf, "\npackage %s\n", opts.Package)
+
+	if body.Len() > 0 {
+		buf.WriteString("\nimport (\n\t\"github.com/jackc/pgx/v5\"\n)\n")
+	}
+
+	buf.Write(body.Bytes())
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w", err)
+	}
+
+	return src, nil
+}
@@ -0,0 +1,35 @@
+package pgproto_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/crewlinker/pgproto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratePgxScannersGolden(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "pgx_scan_select.sql"))
+	require.NoError(t, err)
+
+	actions, err := pgproto.ParseFullTyped(data)
+	require.NoError(t, err)
+
+	src, err := pgproto.GeneratePgxScanners(actions, pgproto.GoOptions{Package: "users"})
+	require.NoError(t, err)
+
+	golden(t, "pgx_scan_select.sql.go", src)
+}
+
+func TestGeneratePgxScannersResultOrder(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT b::text AS b_2, a::int4 AS a_1, c::int4[] AS c_3 FROM t`))
+	require.NoError(t, err)
+
+	src, err := pgproto.GeneratePgxScanners(actions, pgproto.GoOptions{Package: "t"})
+	require.NoError(t, err)
+	require.Contains(t, string(src), "row.Scan(&res.B, &res.A, &res.C)")
+
+	src, err = pgproto.GeneratePgxScanners(nil, pgproto.GoOptions{Package: "t"})
+	require.NoError(t, err)
+	require.NotContains(t, string(src), "import")
+}
@@ -0,0 +1,57 @@
+package pgproto
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// PrepareOptions configure the generation of "PREPARE" statements.
+type PrepareOptions struct {
+	// GeneratorName is mentioned in the "Code generated" banner of the file, it defaults to "pgproto".
+	GeneratorName string
+}
+
+// GeneratePrepares generates a SQL script with a "PREPARE" statement for each action, named after its [MethodNames].
+// The parameter types are the types of the inputs in the order of their position, and the statement is the action's
+// SQL with its named arguments rewritten to positional arguments. Running the script registers all actions as
+// prepared statements for the session.
+//
+// The names are quoted, so they keep their case: execute them with the quoted name too, e.g: `EXECUTE "SelectUsers"`,
+// since the unquoted SelectUsers refers to "selectusers". Like the other generators it takes options, e.g: for the
+// name of the generator in the banner of the script.
+func GeneratePrepares(actions []Action, opts PrepareOptions) ([]byte, error) {
+	var buf bytes.Buffer
+	writeBanner(&buf, "--", opts.GeneratorName)
+
+	for idx, name := range MethodNames(actions) {
+		sql, _, err := positionalSQL(actions[idx])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		inputs := slices.SortedFunc(slices.Values(actions[idx].getInputs()), func(a, b *Input) int {
+			return cmp.Compare(a.Number, b.Number)
+		})
+
+		var params string
+		if len(inputs) > 0 {
+			params = "(" + strings.Join(lo.Map(inputs, func(inp *Input, _ int) string {
+				return inp.typeString()
+			}), ", ") + ")"
+		}
+
+		fmt.Fprintf(&buf, "\nPREPARE %s%s AS\n%s;\n", quoteIdentifier(name), params, sql)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// quoteIdentifier quotes the name as a SQL identifier, double quotes in the name are escaped by doubling them.
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
@@ -0,0 +1,33 @@
+package pgproto_test
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/crewlinker/pgproto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeneratePreparesGolden(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "prepare_select.sql"))
+	require.NoError(t, err)
+
+	actions, err := pgproto.ParseFullTyped(data)
+	require.NoError(t, err)
+
+	sql, err := pgproto.GeneratePrepares(actions, pgproto.PrepareOptions{})
+	require.NoError(t, err)
+
+	golden(t, "prepare_select.sql.prepare", sql)
+}
+
+func TestGeneratePreparesBanner(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1 FROM users`))
+	require.NoError(t, err)
+
+	sql, err := pgproto.GeneratePrepares(actions, pgproto.PrepareOptions{GeneratorName: "usergen"})
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(string(sql), "-- Code generated by usergen; DO NOT EDIT.\n"))
+	require.Contains(t, string(sql), "\nPREPARE \"SelectUsers\" AS\n")
+}
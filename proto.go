@@ -0,0 +1,460 @@
+package pgproto
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"fmt"
+	"maps"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/samber/lo"
+)
+
+// ProtoOptions configure the generation of protobuf definitions.
+type ProtoOptions struct {
+	// Package is the protobuf package of the generated file, it is omitted when empty.
+	Package string
+	// GeneratorName is mentioned in the "Code generated" banner of the file, it defaults to "pgproto".
+	GeneratorName string
+	// BigintAsString maps "int8" onto a protobuf string, so it survives JSON clients that can't represent 64-bit
+	// integers precisely (e.g: JavaScript). Go code keeps using int64.
+	BigintAsString bool
+	// IncludeFingerprintComment emits a comment with the [Action.Fingerprint] above each message, such that tools can
+	// detect generated code that is out-of-date.
+	IncludeFingerprintComment bool
+	// MessagePrefix is prepended to the name of each generated message.
+	MessagePrefix string
+	// OneofGroups maps the names of oneof groups onto the numbers of the outputs that are part of it. This is useful
+	// for queries where exactly one of several outputs is not NULL.
+	OneofGroups map[string][]int
+	// ServiceName is the name of a service with an RPC for each action, it is omitted when empty. The RPCs are named
+	// after the action's [MethodNames] and receive a request message with a field for each input. Actions that may
+	// return more than one row stream their responses, see [ConnectMethods].
+	ServiceName string
+	// ReservedNumbers maps the names of actions (see [MethodNames]) onto field numbers that may not be used by their
+	// outputs, e.g: because they belonged to fields that have since been removed. They are declared as reserved in the
+	// action's response message.
+	ReservedNumbers map[string][]int
+	// DeprecatedNumbers maps the names of actions (see [MethodNames]) onto the numbers of fields that are on their way
+	// out, e.g: as listed by a previous version of the generated code. Outputs that still use such a number are marked
+	// deprecated, otherwise the number is declared as reserved in the action's response message such that it won't be
+	// re-used.
+	DeprecatedNumbers map[string][]int
+}
+
+// GenerateProto generates a protobuf file that declares a response message for each action. The message is named
+// after the action's [MethodNames], is documented by the action's comment and has a field for each output. The
+// output's number is used as the field number and its name, without the number suffix, as the field name.
+func GenerateProto(actions []Action, opts ProtoOptions) ([]byte, error) {
+	var body bytes.Buffer
+
+	imports, names := map[string]bool{}, MethodNames(actions)
+	for idx, name := range names {
+		body.WriteString("\n")
+		writeComment(&body, "", actions[idx].info().Comment)
+
+		if opts.IncludeFingerprintComment {
+			fmt.Fprintf(&body, "// pgproto-fingerprint: %s\n", actions[idx].Fingerprint())
+		}
+
+		fmt.Fprintf(&body, "message %s%sResponse {\n", opts.MessagePrefix, name)
+
+		outputs := markDeprecated(actions[idx].getOutputs(), opts.DeprecatedNumbers[name])
+		if err := writeProtoReserved(&body, outputs, opts.ReservedNumbers[name], opts.DeprecatedNumbers[name]); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		if err := writeProtoFields(&body, outputs, opts, imports); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		body.WriteString("}\n")
+
+		if opts.ServiceName != "" {
+			fmt.Fprintf(&body, "\nmessage %s%sRequest {\n", opts.MessagePrefix, name)
+
+			if err := writeProtoInputs(&body, actions[idx].getInputs(), opts, imports); err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+
+			body.WriteString("}\n")
+		}
+	}
+
+	if opts.ServiceName != "" {
+		writeProtoService(&body, actions, names, opts)
+	}
+
+	var buf bytes.Buffer
+	writeBanner(&buf, "//", opts.GeneratorName)
+	buf.WriteString("\nsyntax = \"proto3\";\n")
+
+	if opts.Package != "" {
+		fmt.Fprintf(&buf, "\npackage %s;\n", opts.Package)
+	}
+
+	if len(imports) > 0 {
+		buf.WriteString("\n")
+
+		for _, imp := range slices.Sorted(maps.Keys(imports)) {
+			fmt.Fprintf(&buf, "import %q;\n", imp)
+		}
+	}
+
+	buf.Write(body.Bytes())
+
+	return buf.Bytes(), nil
+}
+
+// ErrDuplicateMethodName is returned when a statement is named with a "-- name:" comment, but its [MethodName] is
+// already used by another statement. The generated method would have to be renamed, see [MethodNames].
+var ErrDuplicateMethodName = errors.New("duplicate method name")
+
+// ValidateGeneratable parses the input with the options and checks that the type of every input and output can be
+// mapped onto a protobuf type, and that every named statement gets its declared name as method name, such that code
+// can be generated for it. All problems are reported in a single (joined) error.
+//
+// Unnamed statements that synthesize the same method name are not reported: generation de-duplicates them with a
+// counter suffix, e.g: two selects from "users" become "SelectUsers" and "SelectUsers2", see [MethodNames]. Name the
+// statements with a "-- name:" comment if the methods need names that don't depend on the order of the statements.
+func ValidateGeneratable(input []byte, opts ProtoOptions, parseOpts ...Option) error {
+	actions, err := ParseFullTyped(input, parseOpts...)
+	if err != nil {
+		return err
+	}
+
+	names := MethodNames(actions)
+	for idx, action := range actions {
+		location, name := action.info().location, MethodName(action)
+		if other := slices.Index(names, name); action.info().Name != "" && names[idx] != name && other >= 0 {
+			line, column := linePosition(input, actions[other].info().location)
+			err = errors.Join(err, positionErrorf("statement", location, "%w: %s, also used by the statement at %d:%d",
+				ErrDuplicateMethodName, name, line, column))
+		}
+
+		for _, inp := range action.getInputs() {
+			if _, terr := ProtoType(inp.Type, opts); terr != nil {
+				err = errors.Join(err, positionErrorf("statement", location, "input '%s': %w", inp.Name, terr))
+			}
+		}
+
+		for _, output := range action.getOutputs() {
+			if _, terr := ProtoType(output.Type, opts); terr != nil {
+				err = errors.Join(err, positionErrorf("statement", location, "output '%s': %w", output.Name, terr))
+			}
+		}
+	}
+
+	resolvePositions(err, input)
+
+	return err
+}
+
+// ErrReservedNumberUsed is returned when an output uses a field number that is reserved.
+var ErrReservedNumberUsed = errors.New("reserved number used")
+
+// writeProtoReserved writes the reserved field numbers of a message: its reserved numbers and its deprecated numbers
+// that are no longer used by any output. It errors when any of the outputs uses a reserved number.
+func writeProtoReserved(buf *bytes.Buffer, outputs []*Output, reserved, deprecated []int) error {
+	for _, output := range outputs {
+		if slices.Contains(reserved, output.Number) {
+			return fmt.Errorf("%w: output '%s' has number %d", ErrReservedNumberUsed, output.Name, output.Number)
+		}
+	}
+
+	reserved = reservedNumbers(outputs, reserved, deprecated)
+	if len(reserved) == 0 {
+		return nil
+	}
+
+	fmt.Fprintf(buf, "  reserved %s;\n", strings.Join(lo.Map(reserved,
+		func(num int, _ int) string { return strconv.Itoa(num) }), ", "))
+
+	return nil
+}
+
+// reservedNumbers returns the sorted field numbers that are reserved in the message for the outputs: the reserved
+// numbers and the deprecated numbers that are no longer used by any output.
+func reservedNumbers(outputs []*Output, reserved, deprecated []int) []int {
+	used := map[int]bool{}
+	for _, output := range outputs {
+		used[output.Number] = true
+	}
+
+	reserved = slices.Clone(reserved)
+	for _, num := range deprecated {
+		if !used[num] {
+			reserved = append(reserved, num)
+		}
+	}
+
+	slices.Sort(reserved)
+
+	return slices.Compact(reserved)
+}
+
+// markDeprecated returns the outputs with those that use one of the deprecated numbers marked as deprecated. The
+// marked outputs are copies, the action's outputs are left as is.
+func markDeprecated(outputs []*Output, deprecated []int) []*Output {
+	return lo.Map(outputs, func(output *Output, _ int) *Output {
+		if !slices.Contains(deprecated, output.Number) {
+			return output
+		}
+
+		marked := *output
+		marked.Deprecated = true
+
+		return &marked
+	})
+}
+
+// ErrPositionNumbersNotStable is returned when generating protobuf for outputs that are numbered by their position,
+// since their field numbers would change when the columns are reordered.
+var ErrPositionNumbersNotStable = errors.New("output numbered by position, use a number suffix for a stable number")
+
+// ErrProtoFieldCollision is returned when the names of different outputs (or inputs) map onto the same protobuf field
+// name, e.g: "userId_1" and "user_id_2" both become "user_id".
+var ErrProtoFieldCollision = errors.New("protobuf field name collision")
+
+// checkProtoFieldNames checks that each output maps onto a distinct protobuf field name with a stable number.
+func checkProtoFieldNames(outputs []*Output) error {
+	fieldOf := map[string]*Output{}
+	for _, output := range outputs {
+		if output.positional {
+			return fmt.Errorf("%w: output '%s'", ErrPositionNumbersNotStable, output.Name)
+		}
+
+		field := snakeCase(output.BaseName)
+		if other, exists := fieldOf[field]; exists {
+			return fmt.Errorf("%w: outputs '%s' and '%s' both map onto '%s'",
+				ErrProtoFieldCollision, other.Name, output.Name, field)
+		}
+
+		fieldOf[field] = output
+	}
+
+	return nil
+}
+
+// writeProtoInputs writes a field for each input, in the order of their number. Array inputs are declared as repeated
+// fields. Positional inputs have no name, their field is named after their position, e.g: "arg_1".
+func writeProtoInputs(buf *bytes.Buffer, inputs []*Input, opts ProtoOptions, imports map[string]bool) error {
+	fieldOf := map[string]*Input{}
+	for _, inp := range slices.SortedFunc(slices.Values(inputs), func(a, b *Input) int {
+		return cmp.Compare(a.Number, b.Number)
+	}) {
+		protoType, err := ProtoType(inp.Type, opts)
+		if err != nil {
+			return fmt.Errorf("input '%s': %w", inp.Name, err)
+		}
+
+		if imp, ok := wellKnownImports[protoType]; ok {
+			imports[imp] = true
+		}
+
+		var label string
+
+		switch {
+		case inp.ArrayDims > 1:
+			return fmt.Errorf("input '%s': %w", inp.Name, ErrMultiDimensionalArray)
+		case inp.IsArray:
+			label = "repeated "
+		}
+
+		field := snakeCase(inp.BaseName)
+		if field == "" {
+			field = "arg_" + strconv.Itoa(inp.Number)
+		}
+
+		if other, exists := fieldOf[field]; exists {
+			return fmt.Errorf("%w: inputs '%s' and '%s' both map onto '%s'",
+				ErrProtoFieldCollision, other.Name, inp.Name, field)
+		}
+
+		fieldOf[field] = inp
+
+		fmt.Fprintf(buf, "  %s%s %s = %d;\n", label, protoType, field, inp.Number)
+	}
+
+	return nil
+}
+
+// writeProtoService writes the service with an RPC for each action, documented by the action's comment.
+func writeProtoService(buf *bytes.Buffer, actions []Action, names []string, opts ProtoOptions) {
+	fmt.Fprintf(buf, "\nservice %s {\n", opts.ServiceName)
+
+	for idx, name := range names {
+		var stream string
+		if serverStreaming(actions[idx]) {
+			stream = "stream "
+		}
+
+		writeComment(buf, "  ", actions[idx].info().Comment)
+		fmt.Fprintf(buf, "  rpc %s(%s%sRequest) returns (%s%s%sResponse);\n",
+			name, opts.MessagePrefix, name, stream, opts.MessagePrefix, name)
+	}
+
+	buf.WriteString("}\n")
+}
+
+// ErrOneofField is returned when an output can't be part of a oneof group.
+var ErrOneofField = errors.New("invalid oneof field")
+
+// writeProtoFields writes a field for each output, outputs that are part of a oneof group are written together in
+// a oneof at the position of the group's first output. The imports required for the field types are recorded.
+func writeProtoFields(buf *bytes.Buffer, outputs []*Output, opts ProtoOptions, imports map[string]bool) error {
+	if err := checkProtoFieldNames(outputs); err != nil {
+		return err
+	}
+
+	groupOf := map[int]string{}
+	for _, group := range slices.Sorted(maps.Keys(opts.OneofGroups)) {
+		for _, num := range opts.OneofGroups[group] {
+			if other, exists := groupOf[num]; exists {
+				return fmt.Errorf("%w: %d is part of both oneof '%s' and '%s'", ErrOneofField, num, other, group)
+			}
+
+			groupOf[num] = group
+		}
+	}
+
+	written := map[string]bool{}
+	for _, output := range outputs {
+		group, inGroup := groupOf[output.Number]
+		if !inGroup {
+			if err := writeProtoField(buf, "  ", output, opts, imports); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if written[group] {
+			continue
+		}
+
+		written[group] = true
+
+		fmt.Fprintf(buf, "  oneof %s {\n", group)
+
+		for _, member := range outputs {
+			if groupOf[member.Number] != group {
+				continue
+			}
+
+			if member.IsArray {
+				return fmt.Errorf("%w: output '%s' is an array", ErrOneofField, member.Name)
+			}
+
+			member := *member
+			member.Nullable = false // members of a oneof always track presence
+
+			if err := writeProtoField(buf, "    ", &member, opts, imports); err != nil {
+				return err
+			}
+		}
+
+		buf.WriteString("  }\n")
+	}
+
+	return nil
+}
+
+// writeProtoField writes the documentation and declaration of the field for an output.
+func writeProtoField(
+	buf *bytes.Buffer, indent string, output *Output, opts ProtoOptions, imports map[string]bool,
+) error {
+	field, err := output.ProtoField(opts)
+	if err != nil {
+		return err
+	}
+
+	if imp, ok := wellKnownImports[lo.Must(ProtoType(output.Type, opts))]; ok {
+		imports[imp] = true
+	}
+
+	writeComment(buf, indent, output.Doc)
+	fmt.Fprintf(buf, "%s%s\n", indent, field)
+
+	return nil
+}
+
+// GenerateProtoForDir generates a protobuf file for each of the parsed SQL files, keyed by the SQL file's name with
+// its extension replaced by ".proto". Message names are prefixed with the (camel-cased) name of the file, such that
+// they don't collide when the files share a package. The files are generated in order of their name, the error of the
+// first file that fails is returned.
+func GenerateProtoForDir(parsed map[string][]Action, opts ProtoOptions) (map[string][]byte, error) {
+	files := make(map[string][]byte, len(parsed))
+	for _, filename := range slices.Sorted(maps.Keys(parsed)) {
+		actions := parsed[filename]
+		stem := strings.TrimSuffix(filename, filepath.Ext(filename))
+
+		fileOpts := opts
+		fileOpts.MessagePrefix += camelCase(filepath.Base(stem))
+
+		data, err := GenerateProto(actions, fileOpts)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+
+		files[stem+".proto"] = data
+	}
+
+	return files, nil
+}
+
+// ErrMultiDimensionalArray is returned when an output with a multi-dimensional array type is mapped onto a protobuf
+// field, which can only be repeated once.
+var ErrMultiDimensionalArray = errors.New("multi-dimensional arrays are not supported")
+
+// ProtoField returns the protobuf field declaration for the output, e.g: "int32 user_id = 3;". Arrays are declared
+// as repeated fields and nullable outputs as optional fields. Deprecated outputs get the deprecated option.
+func (o *Output) ProtoField(opts ProtoOptions) (string, error) {
+	protoType, err := ProtoType(o.Type, opts)
+	if err != nil {
+		return "", fmt.Errorf("output '%s': %w", o.Name, err)
+	}
+
+	var label string
+
+	switch {
+	case o.ArrayDims > 1:
+		return "", fmt.Errorf("output '%s': %w", o.Name, ErrMultiDimensionalArray)
+	case o.IsArray:
+		label = "repeated "
+	case o.Nullable:
+		label = "optional "
+	}
+
+	var fieldOpts string
+	if o.Deprecated {
+		fieldOpts = " [deprecated = true]"
+	}
+
+	return fmt.Sprintf("%s%s %s = %d%s;", label, protoType, snakeCase(o.BaseName), o.Number, fieldOpts), nil
+}
+
+// writeBanner writes the comment that marks a file as generated, following the convention of "go generate". The
+// comment starts with the comment marker of the generated language, e.g: "//" or "--".
+func writeBanner(buf *bytes.Buffer, marker, generator string) {
+	if generator == "" {
+		generator = "pgproto"
+	}
+
+	fmt.Fprintf(buf, "%s Code generated by %s; DO NOT EDIT.\n", marker, generator)
+}
+
+// writeComment writes the (multi-line) documentation as a comment.
+func writeComment(buf *bytes.Buffer, indent, doc string) {
+	if doc == "" {
+		return
+	}
+
+	for _, line := range strings.Split(strings.TrimSpace(doc), "\n") {
+		fmt.Fprintf(buf, "%s// %s\n", indent, strings.TrimSpace(line))
+	}
+}
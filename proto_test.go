@@ -0,0 +1,317 @@
+package pgproto_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/crewlinker/pgproto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateProtoWithDocs(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped(
+		[]byte(`SELECT id::uuid AS id_1, created::timestamptz AS created_at_2 FROM users`),
+		pgproto.WithDocs(map[string]string{"select:1": "ID of the user."}))
+	require.NoError(t, err)
+	require.Equal(t, "select:1", actions[0].(*pgproto.SelectAction).Outputs[0].StableID())
+
+	out, err := pgproto.GenerateProto(actions, pgproto.ProtoOptions{Package: "users.v1"})
+	require.NoError(t, err)
+	require.Equal(t, `// Code generated by pgproto; DO NOT EDIT.
+
+syntax = "proto3";
+
+package users.v1;
+
+import "google/protobuf/timestamp.proto";
+
+message SelectUsersResponse {
+  // ID of the user.
+  string id = 1;
+  google.protobuf.Timestamp created_at = 2;
+}
+`, string(out))
+}
+
+func TestProtoField(t *testing.T) {
+	for _, tt := range []struct {
+		output *pgproto.Output
+		exp    string
+	}{
+		{&pgproto.Output{Number: 3, BaseName: "user_id", Type: pgproto.TypeRef{Name: "int4"}}, "int32 user_id = 3;"},
+		{&pgproto.Output{Number: 1, BaseName: "tags", Type: pgproto.TypeRef{Name: "text"}, IsArray: true, ArrayDims: 1},
+			"repeated string tags = 1;"},
+		{&pgproto.Output{Number: 2, BaseName: "nick", Type: pgproto.TypeRef{Name: "text"}, Nullable: true},
+			"optional string nick = 2;"},
+	} {
+		t.Run(tt.exp, func(t *testing.T) {
+			field, err := tt.output.ProtoField(pgproto.ProtoOptions{})
+			require.NoError(t, err)
+			require.Equal(t, tt.exp, field)
+		})
+	}
+}
+
+func TestProtoFieldMultiDimensionalArray(t *testing.T) {
+	_, err := (&pgproto.Output{Name: "grid_1", Type: pgproto.TypeRef{Name: "int4"}, IsArray: true, ArrayDims: 2}).
+		ProtoField(pgproto.ProtoOptions{})
+	require.ErrorIs(t, err, pgproto.ErrMultiDimensionalArray)
+}
+
+func TestUnicodeAlias(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT name::text AS "café_1", "naïve"::bool AS "naïve_größe_2" FROM menu`))
+	require.NoError(t, err)
+
+	outputs := actions[0].(*pgproto.SelectAction).Outputs
+	require.Equal(t, 1, outputs[0].Number)
+	require.Equal(t, "café", outputs[0].BaseName)
+
+	field, err := outputs[0].ProtoField(pgproto.ProtoOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "string cafe = 1;", field)
+
+	field, err = outputs[1].ProtoField(pgproto.ProtoOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "bool naive_gro_e = 2;", field)
+}
+
+func TestGenerateProtoFingerprintComment(t *testing.T) {
+	parse := func(sql string) []pgproto.Action {
+		actions, err := pgproto.ParseFullTyped([]byte(sql))
+		require.NoError(t, err)
+
+		return actions
+	}
+
+	actions := parse(`SELECT id::uuid AS id_1 FROM users`)
+	fprint := actions[0].Fingerprint()
+	require.Len(t, fprint, 16)
+	require.Equal(t, fprint, parse(`-- some comment
+		SELECT  id::uuid AS id_1
+		FROM users`)[0].Fingerprint())
+	require.NotEqual(t, fprint, parse(`SELECT id::uuid AS id_2 FROM users`)[0].Fingerprint())
+	require.NotEqual(t, fprint, parse(`SELECT id::text AS id_1 FROM users`)[0].Fingerprint())
+
+	out, err := pgproto.GenerateProto(actions, pgproto.ProtoOptions{IncludeFingerprintComment: true})
+	require.NoError(t, err)
+	require.Equal(t, `// Code generated by pgproto; DO NOT EDIT.
+
+syntax = "proto3";
+
+// pgproto-fingerprint: `+fprint+`
+message SelectUsersResponse {
+  string id = 1;
+}
+`, string(out))
+}
+
+func TestGenerateProtoForDir(t *testing.T) {
+	parsed := map[string][]pgproto.Action{}
+	for filename, sql := range map[string]string{
+		"queries/users.sql":       `SELECT id::uuid AS id_1 FROM users`,
+		"queries/order_items.sql": `SELECT id::uuid AS id_1 FROM users`,
+	} {
+		actions, err := pgproto.ParseFullTyped([]byte(sql))
+		require.NoError(t, err)
+
+		parsed[filename] = actions
+	}
+
+	files, err := pgproto.GenerateProtoForDir(parsed, pgproto.ProtoOptions{Package: "queries.v1"})
+	require.NoError(t, err)
+	require.Len(t, files, 2)
+	require.Contains(t, string(files["queries/users.proto"]), "message UsersSelectUsersResponse {")
+	require.Contains(t, string(files["queries/order_items.proto"]), "message OrderItemsSelectUsersResponse {")
+}
+
+func TestGenerateProtoForDirFirstError(t *testing.T) {
+	parsed := map[string][]pgproto.Action{}
+	for _, filename := range []string{"queries/c.sql", "queries/a.sql", "queries/b.sql"} {
+		actions, err := pgproto.ParseFullTyped([]byte(`SELECT a::int4 AS "userId_1", b::int4 AS user_id_2 FROM users`))
+		require.NoError(t, err)
+
+		parsed[filename] = actions
+	}
+
+	for range 10 {
+		_, err := pgproto.GenerateProtoForDir(parsed, pgproto.ProtoOptions{})
+		require.ErrorIs(t, err, pgproto.ErrProtoFieldCollision)
+		require.ErrorContains(t, err, "queries/a.sql: ")
+	}
+}
+
+func TestGenerateProtoGolden(t *testing.T) {
+	for _, tt := range []struct {
+		filename string
+		opts     pgproto.ProtoOptions
+	}{
+		{filename: "oneof_select.sql", opts: pgproto.ProtoOptions{
+			Package:     "payments.v1",
+			OneofGroups: map[string][]int{"method": {2, 3}},
+		}},
+		{filename: "deprecated_fields_select.sql", opts: pgproto.ProtoOptions{
+			Package:           "users.v2",
+			ReservedNumbers:   map[string][]int{"GetUser": {5}},
+			DeprecatedNumbers: map[string][]int{"GetUser": {2, 3}},
+		}},
+		{filename: "service.sql", opts: pgproto.ProtoOptions{
+			Package:     "users.v1",
+			ServiceName: "UserService",
+		}},
+	} {
+		t.Run(tt.filename, func(t *testing.T) {
+			data, err := testdata.ReadFile(filepath.Join("testdata", tt.filename))
+			require.NoError(t, err)
+
+			actions, err := pgproto.ParseFullTyped(data)
+			require.NoError(t, err)
+
+			actProto, err := pgproto.GenerateProto(actions, tt.opts)
+			require.NoError(t, err)
+
+			golden(t, tt.filename+".proto", actProto)
+		})
+	}
+}
+
+func TestGenerateProtoReservedNumbers(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1, name::text AS name_4 FROM users`))
+	require.NoError(t, err)
+
+	out, err := pgproto.GenerateProto(actions,
+		pgproto.ProtoOptions{ReservedNumbers: map[string][]int{"SelectUsers": {3, 2}}})
+	require.NoError(t, err)
+	require.Contains(t, string(out), "message SelectUsersResponse {\n  reserved 2, 3;\n  string id = 1;\n")
+
+	_, err = pgproto.GenerateProto(actions, pgproto.ProtoOptions{ReservedNumbers: map[string][]int{"SelectUsers": {2, 4}}})
+	require.ErrorIs(t, err, pgproto.ErrReservedNumberUsed)
+	require.ErrorContains(t, err, "SelectUsers: reserved number used: output 'name_4' has number 4")
+}
+
+func TestGenerateProtoDeprecatedNumbersPerMessage(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`-- name: GetUser
+SELECT id::uuid AS id_1, nick::text AS nick_2 FROM users;
+-- name: GetOrder
+SELECT id::uuid AS id_1, total::int4 AS total_3 FROM orders;`))
+	require.NoError(t, err)
+
+	out, err := pgproto.GenerateProto(actions, pgproto.ProtoOptions{
+		DeprecatedNumbers: map[string][]int{"GetUser": {2, 3}},
+	})
+	require.NoError(t, err)
+	require.Contains(t, string(out), "message GetUserResponse {\n  reserved 3;\n  string id = 1;\n"+
+		"  string nick = 2 [deprecated = true];\n}\n")
+	require.Contains(t, string(out), "message GetOrderResponse {\n  string id = 1;\n  int32 total = 3;\n}\n")
+}
+
+func TestGenerateProtoFieldCollision(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT a::int4 AS "userId_1", b::int4 AS user_id_2 FROM users`))
+	require.NoError(t, err)
+
+	_, err = pgproto.GenerateProto(actions, pgproto.ProtoOptions{})
+	require.ErrorIs(t, err, pgproto.ErrProtoFieldCollision)
+	require.ErrorContains(t, err, "outputs 'userId_1' and 'user_id_2' both map onto 'user_id'")
+
+	actions, err = pgproto.ParseFullTyped([]byte(
+		`SELECT id::uuid AS id_1 FROM users WHERE a = @"userId_1"::uuid AND b = @user_id_2::uuid`))
+	require.NoError(t, err)
+
+	_, err = pgproto.GenerateProto(actions, pgproto.ProtoOptions{ServiceName: "UserService"})
+	require.ErrorIs(t, err, pgproto.ErrProtoFieldCollision)
+	require.ErrorContains(t, err, "inputs 'userId_1' and 'user_id_2' both map onto 'user_id'")
+}
+
+func TestGenerateProtoArrayAgg(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "array_agg_select.sql"))
+	require.NoError(t, err)
+
+	actions, err := pgproto.ParseFullTyped(data)
+	require.NoError(t, err)
+
+	xs := actions[0].(*pgproto.SelectAction).Outputs[1]
+	require.True(t, xs.Aggregate)
+	require.Equal(t, 1, xs.ArrayDims)
+
+	out, err := pgproto.GenerateProto(actions, pgproto.ProtoOptions{})
+	require.NoError(t, err)
+	require.Contains(t, string(out), "  repeated int32 xs = 2;\n")
+}
+
+func TestGenerateProtoStatementComment(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte("-- Lists all users.\nSELECT id::int4 AS id_1 FROM users"))
+	require.NoError(t, err)
+
+	out, err := pgproto.GenerateProto(actions, pgproto.ProtoOptions{})
+	require.NoError(t, err)
+	require.Contains(t, string(out), "\n// Lists all users.\nmessage SelectUsersResponse {\n")
+}
+
+func TestValidateGeneratable(t *testing.T) {
+	require.NoError(t, pgproto.ValidateGeneratable([]byte(`SELECT id::uuid AS id_1 FROM users`), pgproto.ProtoOptions{}))
+
+	err := pgproto.ValidateGeneratable([]byte(`
+SELECT id::uuid AS id_1 FROM users WHERE area && @area_1::box;
+SELECT loc::point AS loc_1, id::uuid AS id_2 FROM places;`), pgproto.ProtoOptions{})
+	require.ErrorIs(t, err, pgproto.ErrUnsupportedType)
+	require.Equal(t, "statement at 2:1: input 'area_1': unsupported type: box\n"+
+		"statement at 3:1: output 'loc_1': unsupported type: point", err.Error())
+
+	err = pgproto.ValidateGeneratable([]byte(`SELECT id AS id_1 FROM users`), pgproto.ProtoOptions{})
+	require.ErrorIs(t, err, pgproto.ErrColumnWithoutCast)
+
+	email := []byte(`SELECT email::email AS email_1 FROM users`)
+	require.ErrorIs(t, pgproto.ValidateGeneratable(email, pgproto.ProtoOptions{}), pgproto.ErrUnsupportedType)
+	require.NoError(t, pgproto.ValidateGeneratable(email, pgproto.ProtoOptions{},
+		pgproto.WithDomains(map[string]pgproto.DomainInfo{"email": {Type: pgproto.TypeRef{Name: "text"}}})))
+}
+
+func TestValidateGeneratableMethodNames(t *testing.T) {
+	unnamed := []byte(`SELECT id::uuid AS id_1 FROM users;
+SELECT name::text AS name_1 FROM users;
+-- name: UserByID
+SELECT id::uuid AS id_1 FROM users WHERE id = @id_1::uuid;`)
+	require.NoError(t, pgproto.ValidateGeneratable(unnamed, pgproto.ProtoOptions{}))
+
+	actions, err := pgproto.ParseFullTyped(unnamed)
+	require.NoError(t, err)
+	require.Equal(t, []string{"SelectUsers", "SelectUsers2", "UserByID"}, pgproto.MethodNames(actions))
+
+	err = pgproto.ValidateGeneratable([]byte(`SELECT id::uuid AS id_1 FROM users;
+SELECT name::text AS name_1 FROM users;
+-- name: SelectUsers2
+SELECT id::uuid AS id_1 FROM users WHERE id = @id_1::uuid;`), pgproto.ProtoOptions{})
+	require.ErrorIs(t, err, pgproto.ErrDuplicateMethodName)
+	require.Equal(t, "statement at 3:1: duplicate method name: SelectUsers2, also used by the statement at 2:1",
+		err.Error())
+
+	require.NoError(t, pgproto.ValidateGeneratable([]byte(`-- name: UserIDs
+SELECT id::uuid AS id_1 FROM users;
+-- name: UserNames
+SELECT name::text AS name_1 FROM users;`), pgproto.ProtoOptions{}))
+}
+
+func TestGenerateProtoFieldPerOutput(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1, name::text AS name_2 FROM users`))
+	require.NoError(t, err)
+
+	out, err := pgproto.GenerateProto(actions, pgproto.ProtoOptions{})
+	require.NoError(t, err)
+	require.Contains(t, string(out), "message SelectUsersResponse {\n  string id = 1;\n  string name = 2;\n}\n")
+}
+
+func TestGenerateProtoRefusesPositionNumbers(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id, name::text AS name_2 FROM users`),
+		pgproto.WithAutoNumberByPosition())
+	require.NoError(t, err)
+	require.Equal(t, 1, actions[0].(*pgproto.SelectAction).Outputs[0].Number)
+
+	_, err = pgproto.GenerateProto(actions, pgproto.ProtoOptions{})
+	require.ErrorIs(t, err, pgproto.ErrPositionNumbersNotStable)
+	require.ErrorContains(t, err, "output 'id'")
+
+	actions, err = pgproto.ParseFullTyped([]byte(`SELECT id::uuid FROM users`), pgproto.WithInferredAliases())
+	require.NoError(t, err)
+
+	_, err = pgproto.GenerateProto(actions, pgproto.ProtoOptions{})
+	require.ErrorIs(t, err, pgproto.ErrPositionNumbersNotStable)
+}
@@ -0,0 +1,151 @@
+package pgproto
+
+import (
+	"bytes"
+	"cmp"
+	"fmt"
+	"go/format"
+	"slices"
+	"strings"
+	"unicode"
+
+	pgquery "github.com/pganalyze/pg_query_go/v6"
+)
+
+// GenerateRegistry generates a Go file that declares the SQL of each action by its [MethodNames], such that a generic
+// executor can run any of them by name. The SQL has its named arguments rewritten to positional arguments, the names
+// of the inputs are declared in the order of their position.
+func GenerateRegistry(actions []Action, opts GoOptions) ([]byte, error) {
+	var queries, inputs bytes.Buffer
+	for idx, name := range MethodNames(actions) {
+		sql, names, err := positionalSQL(actions[idx])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+
+		fmt.Fprintf(&queries, "\t%q: %q,\n", name, sql)
+		fmt.Fprintf(&inputs, "\t%q: {", name)
+
+		for i, inp := range names {
+			if i > 0 {
+				inputs.WriteString(", ")
+			}
+
+			fmt.Fprintf(&inputs, "%q", inp)
+		}
+
+		inputs.WriteString("},\n")
+	}
+
+	var buf bytes.Buffer
+	writeBanner(&buf, "//", opts.GeneratorName)
+	fmt.Fprintf(&buf, "\npackage %s\n", opts.Package)
+	buf.WriteString("\n// Queries holds the SQL of each query by name, named arguments are rewritten to positional ones.\n")
+	fmt.Fprintf(&buf, "var Queries = map[string]string{\n%s}\n", queries.String())
+	buf.WriteString("\n// QueryInputs holds the names of the inputs of each query by name, in the order of their position.\n")
+	fmt.Fprintf(&buf, "var QueryInputs = map[string][]string{\n%s}\n", inputs.String())
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to format generated code: %w", err)
+	}
+
+	return src, nil
+}
+
+// positionalSQL returns the SQL of the action with its named arguments (e.g: "@id_1::uuid") rewritten to positional
+// arguments (e.g: "$1::uuid"). Inputs are positioned in the order of their number, the names of the inputs are
+// returned in that order. Comments before the statement are removed.
+func positionalSQL(action Action) (string, []string, error) {
+	info := action.info()
+
+	inputs := slices.SortedFunc(slices.Values(action.getInputs()), func(a, b *Input) int {
+		return cmp.Compare(a.Number, b.Number)
+	})
+
+	type param struct {
+		at       int
+		position int
+	}
+
+	names, params := make([]string, 0, len(inputs)), []param{}
+	for idx, inp := range inputs {
+		names = append(names, inp.Name)
+		for _, at := range inp.locations {
+			params = append(params, param{at: int(at - info.location), position: idx + 1})
+		}
+	}
+
+	slices.SortFunc(params, func(a, b param) int { return cmp.Compare(a.at, b.at) })
+
+	var bldr strings.Builder
+
+	sql, prev := info.SQL, 0
+	for _, p := range params {
+		bldr.WriteString(sql[prev:p.at])
+		fmt.Fprintf(&bldr, "$%d", p.position)
+		prev = p.at + 1 + identifierLen(sql[p.at+1:])
+	}
+
+	bldr.WriteString(sql[prev:])
+
+	rewritten, err := withoutLeadingComments(bldr.String())
+	if err != nil {
+		return "", nil, err
+	}
+
+	return rewritten, names, nil
+}
+
+// identifierLen returns the length of the (optionally quoted) identifier at the start of s, including any
+// whitespace in front of it.
+func identifierLen(s string) int {
+	trimmed := strings.TrimLeftFunc(s, unicode.IsSpace)
+	offset := len(s) - len(trimmed)
+
+	if strings.HasPrefix(trimmed, `"`) {
+		for i := 1; i < len(trimmed); i++ {
+			if trimmed[i] != '"' {
+				continue
+			}
+
+			if i+1 < len(trimmed) && trimmed[i+1] == '"' { // escaped quote
+				i++
+
+				continue
+			}
+
+			return offset + i + 1
+		}
+
+		return len(s)
+	}
+
+	end := strings.IndexFunc(trimmed, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' && r != '$'
+	})
+	if end < 0 {
+		return len(s)
+	}
+
+	return offset + end
+}
+
+// withoutLeadingComments removes the comments and whitespace in front of the first token of the SQL.
+func withoutLeadingComments(sql string) (string, error) {
+	scan, err := pgquery.Scan(sql)
+	if err != nil {
+		return "", fmt.Errorf("failed to scan: %w", err)
+	}
+
+	for _, token := range scan.GetTokens() {
+		switch token.GetToken() { //nolint:exhaustive
+		case pgquery.Token_SQL_COMMENT, pgquery.Token_C_COMMENT:
+			continue
+		default:
+			return strings.TrimSpace(sql[token.GetStart():]), nil
+		}
+	}
+
+	return strings.TrimSpace(sql), nil
+}
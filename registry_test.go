@@ -0,0 +1,22 @@
+package pgproto_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/crewlinker/pgproto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateRegistryGolden(t *testing.T) {
+	data, err := testdata.ReadFile(filepath.Join("testdata", "registry.sql"))
+	require.NoError(t, err)
+
+	actions, err := pgproto.ParseFullTyped(data)
+	require.NoError(t, err)
+
+	src, err := pgproto.GenerateRegistry(actions, pgproto.GoOptions{Package: "queries"})
+	require.NoError(t, err)
+
+	golden(t, "registry.sql.go", src)
+}
@@ -0,0 +1,6 @@
+SELECT
+    id::int4 AS id_1,
+    b.name::text AS name_2
+FROM
+    a
+    JOIN b ON a.id = b.id;
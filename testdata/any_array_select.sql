@@ -0,0 +1,7 @@
+SELECT
+    id::uuid AS id_1
+FROM
+    users
+WHERE
+    id = ANY(@ids_1::uuid[])
+    AND tenant = @tenant_2::uuid;
@@ -0,0 +1,7 @@
+SELECT
+    y::text AS y_1,
+    array_agg(x)::int4[] AS xs_2
+FROM
+    t
+GROUP BY
+    y;
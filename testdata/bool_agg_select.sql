@@ -0,0 +1,9 @@
+SELECT
+    g::int4 AS g_1,
+    bool_and(active)::bool AS all_active_2,
+    bool_or(active)::bool AS any_active_3,
+    count(*)::int8 AS total_4
+FROM
+    t
+GROUP BY
+    g;
@@ -0,0 +1,3 @@
+SELECT
+    '\x1234'::bytea AS data_1,
+    E'\\000\\001'::bytea AS escaped_2;
@@ -0,0 +1,9 @@
+SELECT
+    CAST(id AS uuid) AS id_1,
+    CAST(CAST(price AS numeric(10, 2)) AS text) AS price_2,
+    CAST(tags AS text[]) AS tags_3,
+    CAST(created_at AS pg_catalog.timestamptz) AS created_4
+FROM
+    products
+WHERE
+    owner_id = CAST(@owner_1 AS uuid);
@@ -0,0 +1,4 @@
+INSERT INTO foo(name)
+    VALUES (@name_1::text)
+RETURNING
+    (foo.*)::foo AS row_1;
@@ -0,0 +1,5 @@
+SELECT
+    (first || ' ' || last)::text AS full_1,
+    (tags || ARRAY[@tag_1::text])::text[] AS tags_2
+FROM
+    people;
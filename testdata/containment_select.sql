@@ -0,0 +1,6 @@
+SELECT
+    (tags @> @needle_1::text[])::bool AS has_1,
+    (tags <@ @allowed_2::text[])::bool AS within_2,
+    (meta @> @filter_3::jsonb)::bool AS matches_3
+FROM
+    t;
@@ -0,0 +1,5 @@
+SELECT
+    a.id::uuid AS id_1,
+    (SELECT count(*) FROM b WHERE b.a_id = a.id)::int8 AS cnt_2
+FROM
+    a;
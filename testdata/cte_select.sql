@@ -0,0 +1,20 @@
+WITH del AS (
+    DELETE FROM stale
+    WHERE
+        expires_at < @now_1::timestamptz
+    RETURNING
+        id
+),
+recent AS (
+    SELECT
+        id,
+        created_at
+    FROM
+        events
+)
+SELECT
+    del.id::uuid AS id_1,
+    recent.created_at::timestamptz AS "created_2?"
+FROM
+    del
+    LEFT JOIN recent ON recent.id = del.id;
@@ -0,0 +1,8 @@
+UPDATE
+    foo
+SET
+    x = @x_1::int4
+WHERE
+    CURRENT OF my_cursor
+RETURNING
+    id::int4 AS id_1;
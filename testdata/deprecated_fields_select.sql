@@ -0,0 +1,9 @@
+-- name: GetUser
+SELECT
+    id::uuid AS id_1,
+    nick::text AS nick_2,
+    email::text AS email_4
+FROM
+    users
+WHERE
+    id = @id_1::uuid;
@@ -0,0 +1,18 @@
+SELECT
+    a.id::int4 AS id_1,
+    EXISTS (
+        SELECT
+            1
+        FROM
+            b
+        WHERE
+            b.a_id = a.id)::bool AS has_b_2,
+    (NOT EXISTS (
+            SELECT
+                1
+            FROM
+                c
+            WHERE
+                c.a_id = a.id))::bool AS no_c_3
+FROM
+    a;
@@ -0,0 +1,5 @@
+SELECT
+    extract(epoch FROM created_at)::float8 AS ts_1,
+    date_part('year', created_at)::int4 AS year_2
+FROM
+    events;
@@ -0,0 +1,6 @@
+SELECT
+    id::int4 AS id_1
+FROM
+    foo
+ORDER BY
+    id FETCH FIRST 10 ROWS ONLY;
@@ -0,0 +1,6 @@
+SELECT
+    format('%s-%s', a, b)::text AS label_1,
+    concat_ws(', ', a, b, c)::text AS joined_2,
+    upper(substring(a FROM 1 FOR 3))::text AS prefix_3
+FROM
+    t;
@@ -0,0 +1,7 @@
+SELECT
+    id::int4 AS user_id_1,
+    nick::nick AS nick_name_2,
+    created_at::timestamptz AS created_at_3,
+    tags::text[] AS tags_4
+FROM
+    users;
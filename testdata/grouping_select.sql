@@ -0,0 +1,7 @@
+SELECT
+    a::text AS a_1,
+    GROUPING(a)::int4 AS g_2
+FROM
+    t
+GROUP BY
+    ROLLUP (a);
@@ -0,0 +1,9 @@
+SELECT
+    e.id::int4 AS id_1
+FROM
+    events e
+WHERE
+    e.created_at > '2020-01-01'
+    AND e.updated_at > '2020-01-01'::timestamptz
+    AND e.deleted IS NULL
+    AND e.active = TRUE;
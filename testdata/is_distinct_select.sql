@@ -0,0 +1,5 @@
+SELECT
+    (a IS DISTINCT FROM b)::bool AS diff_1,
+    (a IS NOT DISTINCT FROM b)::bool AS same_2
+FROM
+    t;
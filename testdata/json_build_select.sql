@@ -0,0 +1,6 @@
+SELECT
+    json_build_object('a', a, 'b', b)::jsonb AS obj_1,
+    jsonb_build_array(a, b)::jsonb AS arr_2,
+    json_object_agg(a, b)::json AS agg_3
+FROM
+    t;
@@ -0,0 +1,7 @@
+SELECT
+    jsonb_path_query_first(data, '$.items[0]')::jsonb AS first_1,
+    jsonb_path_exists(data, '$.items[*] ? (@.price > $min)', jsonb_build_object('min', @min_price_1::numeric))::bool AS has_expensive_2
+FROM
+    orders
+WHERE
+    data @? '$.items';
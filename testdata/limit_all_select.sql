@@ -0,0 +1,5 @@
+SELECT
+    id::int4 AS id_1
+FROM
+    foo
+LIMIT ALL;
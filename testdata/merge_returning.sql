@@ -0,0 +1,12 @@
+MERGE INTO stock t
+USING deliveries s ON t.item_id = s.item_id
+WHEN MATCHED THEN
+    UPDATE SET
+        qty = t.qty + s.qty
+WHEN NOT MATCHED THEN
+    INSERT (item_id, qty)
+        VALUES (s.item_id, s.qty)
+RETURNING
+    t.item_id::int4 AS item_id_1,
+    t.qty::int4 AS qty_2,
+    s.delivered_at::timestamptz AS delivered_at_3;
@@ -0,0 +1,6 @@
+SELECT
+    id::uuid AS id_1,
+    nick::text AS "nick_2?",
+    tags::text[] AS "tags_3?"
+FROM
+    users;
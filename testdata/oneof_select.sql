@@ -0,0 +1,9 @@
+SELECT
+    p.id::uuid AS id_1,
+    c.number::text AS card_number_2,
+    b.iban::text AS iban_3,
+    p.amount::int8 AS amount_4
+FROM
+    payments p
+    LEFT JOIN cards c ON c.payment_id = p.id
+    LEFT JOIN bank_accounts b ON b.payment_id = p.id;
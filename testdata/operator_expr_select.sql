@@ -0,0 +1,3 @@
+SELECT
+    (|/ 25)::float8 AS root_1,
+    (@ -5)::int4 AS abs_2;
@@ -0,0 +1,10 @@
+SELECT
+    name::text AS name_1,
+    count(*)::int8 AS total_2
+FROM
+    users
+GROUP BY
+    1
+ORDER BY
+    2 DESC,
+    1;
@@ -0,0 +1,5 @@
+INSERT INTO foo (id)
+    OVERRIDING SYSTEM VALUE
+    VALUES (@id_1::int4)
+RETURNING
+    id::int4 AS id_1;
@@ -0,0 +1,8 @@
+SELECT
+    ((id))::int4 AS id_1,
+    ((name)::text) AS name_2,
+    (((a + b)))::int4 AS total_3
+FROM
+    foo
+WHERE
+    id = ((@id_1::int4));
@@ -0,0 +1,6 @@
+SELECT
+    percentile_cont(0.5) WITHIN GROUP (ORDER BY x)::float8 AS median_1,
+    count(*)::int8 AS total_2,
+    lower(min(name))::text AS first_name_3
+FROM
+    t;
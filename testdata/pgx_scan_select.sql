@@ -0,0 +1,13 @@
+-- name: GetUser :one
+SELECT
+    name::text AS name_2,
+    id::uuid AS id_1,
+    tags::text[] AS tags_4,
+    nick::text AS "nick_3?"
+FROM
+    users
+WHERE
+    id = @id_1::uuid;
+
+-- name: TouchUser :exec
+UPDATE users SET seen_at = now() WHERE id = @id_1::uuid;
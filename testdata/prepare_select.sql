@@ -0,0 +1,17 @@
+-- name: GetUser
+SELECT
+    id::uuid AS id_1,
+    name::text AS name_2
+FROM
+    users
+WHERE
+    tenant = @tenant_2::uuid
+    AND id = @id_1::uuid;
+
+-- name: CountUsers
+SELECT
+    count(*)::int8 AS n_1
+FROM
+    users;
+
+INSERT INTO users (id, age) VALUES (@id_1::uuid, @age_2::pg_catalog.int4) RETURNING id::uuid AS id_1
@@ -0,0 +1,8 @@
+WITH active AS (
+    SELECT id FROM public.users WHERE active
+)
+SELECT
+    u.id::uuid AS id_1
+FROM
+    active u
+    JOIN public.teams t ON t.owner_id = u.id;
@@ -0,0 +1,21 @@
+-- name: GetUser
+-- Fetches a single user.
+SELECT
+    id::uuid AS id_1,
+    name::text AS name_2
+FROM
+    users
+WHERE
+    id = @id_1::uuid
+    AND tenant = CAST(@ "tenant_2" AS uuid);
+
+-- name: RenameUser
+UPDATE
+    users
+SET
+    name = @name_2::text
+WHERE
+    id = @id_1::uuid
+    AND name <> @name_2::text
+RETURNING
+    id::uuid AS id_1;
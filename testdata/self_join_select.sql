@@ -0,0 +1,6 @@
+SELECT
+    a1.x::int4 AS x_1,
+    a2.x::int4 AS parent_x_2
+FROM
+    t a1
+    JOIN t a2 ON a2.id = a1.parent_id;
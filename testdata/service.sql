@@ -0,0 +1,30 @@
+-- name: GetUser
+-- Fetches a single user.
+SELECT
+    id::uuid AS id_1,
+    name::text AS name_2
+FROM
+    users
+WHERE
+    id = @id_1::uuid
+LIMIT 1;
+
+-- name: ListUsers
+SELECT
+    id::uuid AS id_1,
+    created_at::timestamptz AS created_at_2
+FROM
+    users
+WHERE
+    tenant = @tenant_1::uuid
+    AND created_at > @since_2::timestamptz;
+
+-- name: CountUsers
+SELECT
+    count(*)::int8 AS n_1
+FROM
+    users;
+
+DELETE FROM users
+WHERE
+    id = @id_1::uuid;
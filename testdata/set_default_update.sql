@@ -0,0 +1,9 @@
+UPDATE
+    foo
+SET
+    status = DEFAULT,
+    name = @name_1::text
+WHERE
+    id = @id_2::int4
+RETURNING
+    status::text AS status_1;
@@ -1,5 +1,5 @@
 DELETE FROM foo
-WHERE id = @id::text
+WHERE id = @id_1::text
 RETURNING
     id::uuid AS id_1;
 
@@ -0,0 +1,7 @@
+SELECT
+    f.id::int4 AS id_1
+FROM (
+    SELECT
+        *
+    FROM
+        foo) f;
@@ -0,0 +1,7 @@
+SELECT
+    ctid::tid AS loc_1,
+    xmin::xid AS version_2
+FROM
+    foo
+WHERE
+    id = @id_1::int4;
@@ -0,0 +1 @@
+TABLE foo;
@@ -0,0 +1,6 @@
+SELECT
+    to_tsvector('english'::regconfig, body)::tsvector AS v_1,
+    plainto_tsquery(@query_1::text)::tsquery AS q_2,
+    'english'::regconfig AS config_3
+FROM
+    posts;
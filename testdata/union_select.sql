@@ -0,0 +1,22 @@
+SELECT
+    id::uuid AS id_1,
+    name::text AS name_2
+FROM
+    customers
+UNION
+SELECT
+    id::uuid AS id_1,
+    NULL::text AS "name_2?"
+FROM
+    suppliers
+WHERE
+    region = @region_1::text
+EXCEPT
+SELECT
+    id::uuid AS id_1,
+    name::text AS name_2
+FROM
+    blocked
+ORDER BY
+    2
+LIMIT 10;
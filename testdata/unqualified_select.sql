@@ -0,0 +1,5 @@
+SELECT
+    u.id::uuid AS id_1
+FROM
+    public.users u
+    JOIN teams t ON t.owner_id = u.id;
@@ -0,0 +1,7 @@
+INSERT INTO foo(id, x)
+    VALUES (@id_1::int4, @x_2::int4)
+ON CONFLICT (id)
+    DO UPDATE SET
+        x = excluded.x
+    RETURNING
+        x::int4 AS x_1;
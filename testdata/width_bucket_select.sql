@@ -0,0 +1,6 @@
+SELECT
+    width_bucket(x, 0, 100, 10)::int4 AS bucket_1,
+    round(x::numeric, 2)::numeric AS rounded_2,
+    greatest(x, y, @floor_1::float8)::float8 AS max_3
+FROM
+    t;
@@ -0,0 +1,10 @@
+SELECT
+    id::uuid AS id_1,
+    row_number() OVER (ORDER BY created)::int8 AS rn_2,
+    sum(amount) OVER (PARTITION BY account_id ORDER BY created ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW)::numeric AS running_3,
+    rank() OVER w::int8 AS rank_4
+FROM
+    events
+WHERE
+    account_id = @account_1::uuid
+WINDOW w AS (PARTITION BY account_id ORDER BY amount DESC);
@@ -0,0 +1,5 @@
+SELECT
+    doc::xml AS doc_1,
+    xpath('/page/title/text()', doc)::xml[] AS titles_2
+FROM
+    pages;
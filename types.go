@@ -0,0 +1,201 @@
+package pgproto
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// ErrUnsupportedType is returned when a Postgres type cannot be mapped onto a type of the generated code.
+var ErrUnsupportedType = errors.New("unsupported type")
+
+// protoTypes maps Postgres types onto protobuf types.
+var protoTypes = map[string]string{
+	"bool":        "bool",
+	"int2":        "int32",
+	"int4":        "int32",
+	"int8":        "int64",
+	"float4":      "float",
+	"float8":      "double",
+	"numeric":     "string",
+	"text":        "string",
+	"varchar":     "string",
+	"bpchar":      "string",
+	"name":        "string",
+	"uuid":        "string",
+	"date":        "string",
+	"time":        "string",
+	"bytea":       "bytes",
+	"timestamp":   "google.protobuf.Timestamp",
+	"timestamptz": "google.protobuf.Timestamp",
+	"interval":    "google.protobuf.Duration",
+	"json":        "google.protobuf.Struct",
+	"jsonb":       "google.protobuf.Struct",
+	// full-text search types are represented by their text form, which can't be queried without casting it back.
+	"tsvector":  "string",
+	"tsquery":   "string",
+	"regconfig": "string",
+	// system column types are represented by their text form, e.g: ctid as "(0,1)" and xmin as "731".
+	"tid": "string",
+	"xid": "string",
+	// the internal single-byte type `"char"`, not to be confused with "char" (which is bpchar).
+	"char": "bytes",
+	// xml documents are represented by their (serialized) text form.
+	"xml": "string",
+}
+
+// goTypes maps Postgres types onto Go types.
+var goTypes = map[string]string{
+	"bool":        "bool",
+	"int2":        "int16",
+	"int4":        "int32",
+	"int8":        "int64",
+	"float4":      "float32",
+	"float8":      "float64",
+	"numeric":     "string",
+	"text":        "string",
+	"varchar":     "string",
+	"bpchar":      "string",
+	"name":        "string",
+	"uuid":        "string",
+	"date":        "time.Time",
+	"time":        "string",
+	"bytea":       "[]byte",
+	"timestamp":   "time.Time",
+	"timestamptz": "time.Time",
+	"interval":    "time.Duration",
+	"json":        "json.RawMessage",
+	"jsonb":       "json.RawMessage",
+	// full-text search types are represented by their text form, which can't be queried without casting it back.
+	"tsvector":  "string",
+	"tsquery":   "string",
+	"regconfig": "string",
+	// system column types are represented by their text form, e.g: ctid as "(0,1)" and xmin as "731".
+	"tid": "string",
+	"xid": "string",
+	// the internal single-byte type `"char"`, not to be confused with "char" (which is bpchar).
+	"char": "byte",
+	// xml documents are represented by their (serialized) text form.
+	"xml": "string",
+}
+
+// wellKnownImports maps protobuf well-known types onto the file that needs to be imported to use them.
+var wellKnownImports = map[string]string{
+	"google.protobuf.Timestamp": "google/protobuf/timestamp.proto",
+	"google.protobuf.Duration":  "google/protobuf/duration.proto",
+	"google.protobuf.Struct":    "google/protobuf/struct.proto",
+}
+
+// WellKnownTypeOutputs returns the outputs of the action whose type maps onto a protobuf well-known type (e.g:
+// google.protobuf.Timestamp), rather than a scalar type. Outputs with an unsupported type are left out.
+func WellKnownTypeOutputs(a Action) (outputs []*Output) {
+	for _, output := range a.getOutputs() {
+		protoType, err := ProtoType(output.Type, ProtoOptions{})
+		if _, ok := wellKnownImports[protoType]; ok && err == nil {
+			outputs = append(outputs, output)
+		}
+	}
+
+	return outputs
+}
+
+// typeAliases maps alternative names of built-in types onto their canonical name, e.g: "integer" onto "int4". The
+// Postgres grammar already resolves the SQL standard names onto "pg_catalog.<canonical name>", the aliases are mapped
+// in case they are written otherwise. Note that "char" is not an alias: quoted it is the internal single-byte type.
+var typeAliases = map[string]string{
+	"int":                         "int4",
+	"integer":                     "int4",
+	"smallint":                    "int2",
+	"bigint":                      "int8",
+	"boolean":                     "bool",
+	"real":                        "float4",
+	"float":                       "float8",
+	"double precision":            "float8",
+	"decimal":                     "numeric",
+	"dec":                         "numeric",
+	"character":                   "bpchar",
+	"character varying":           "varchar",
+	"bit varying":                 "varbit",
+	"timestamp without time zone": "timestamp",
+	"timestamp with time zone":    "timestamptz",
+	"time without time zone":      "time",
+	"time with time zone":         "timetz",
+}
+
+// canonicalType returns the type with its canonical name if enabled, see [WithCanonicalTypeNames]. Built-in types
+// are not qualified with the "pg_catalog" schema, such that "pg_catalog.int4" and "int4" are the same type.
+func canonicalType(typ TypeRef, opts Options) TypeRef {
+	if !opts.CanonicalTypeNames || (typ.Schema != nil && *typ.Schema != "pg_catalog") {
+		return typ
+	}
+
+	if name, ok := typeAliases[typ.Name]; ok {
+		typ.Name = name
+	}
+
+	typ.Schema = nil
+
+	return typ
+}
+
+// CollectTypes returns the distinct types of the inputs and outputs of all actions, sorted by their (qualified) name.
+// Types are compared as written, so "pg_catalog.int4" and "int4" are listed separately, since a type mapper may
+// need to map both. Type modifiers are not part of the identity of a type: the collected types have no
+// [TypeRef.TypeMods], such that "varchar(10)" and "varchar(255)" are both listed as "varchar".
+func CollectTypes(actions []Action) []TypeRef {
+	byName := map[string]TypeRef{}
+	add := func(typ TypeRef) {
+		byName[typ.String()] = TypeRef{Schema: clonePtr(typ.Schema), Name: typ.Name}
+	}
+
+	for _, action := range actions {
+		for _, input := range action.getInputs() {
+			add(input.Type)
+		}
+
+		for _, output := range action.getOutputs() {
+			add(output.Type)
+		}
+	}
+
+	types := make([]TypeRef, 0, len(byName))
+	for _, name := range slices.Sorted(maps.Keys(byName)) {
+		types = append(types, byName[name])
+	}
+
+	return types
+}
+
+// ProtoType returns the protobuf type for a Postgres type. Only types in the "pg_catalog" schema are supported.
+func ProtoType(typ TypeRef, opts ProtoOptions) (string, error) {
+	protoType, err := lookupType(protoTypes, typ)
+	if err != nil {
+		return "", err
+	}
+
+	if opts.BigintAsString && typ.Name == "int8" {
+		return "string", nil
+	}
+
+	return protoType, nil
+}
+
+// GoType returns the Go type for a Postgres type. Only types in the "pg_catalog" schema are supported.
+func GoType(typ TypeRef) (string, error) {
+	return lookupType(goTypes, typ)
+}
+
+// lookupType looks up the Postgres type in a type mapping.
+func lookupType(mapping map[string]string, typ TypeRef) (string, error) {
+	if typ.Schema != nil && *typ.Schema != "pg_catalog" {
+		return "", fmt.Errorf("%w: %s.%s", ErrUnsupportedType, *typ.Schema, typ.Name)
+	}
+
+	mapped, ok := mapping[typ.Name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedType, typ.Name)
+	}
+
+	return mapped, nil
+}
@@ -0,0 +1,171 @@
+package pgproto_test
+
+import (
+	"testing"
+
+	"github.com/crewlinker/pgproto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBigintAsString(t *testing.T) {
+	int8Type := pgproto.TypeRef{Name: "int8"}
+
+	protoType, err := pgproto.ProtoType(int8Type, pgproto.ProtoOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "int64", protoType)
+
+	protoType, err = pgproto.ProtoType(int8Type, pgproto.ProtoOptions{BigintAsString: true})
+	require.NoError(t, err)
+	require.Equal(t, "string", protoType)
+
+	goType, err := pgproto.GoType(int8Type)
+	require.NoError(t, err)
+	require.Equal(t, "int64", goType)
+}
+
+func TestUnsupportedType(t *testing.T) {
+	schema := "public"
+
+	_, err := pgproto.ProtoType(pgproto.TypeRef{Schema: &schema, Name: "int4"}, pgproto.ProtoOptions{})
+	require.ErrorIs(t, err, pgproto.ErrUnsupportedType)
+
+	_, err = pgproto.GoType(pgproto.TypeRef{Name: "foo"})
+	require.ErrorIs(t, err, pgproto.ErrUnsupportedType)
+}
+
+func TestFullTextSearchTypes(t *testing.T) {
+	for _, name := range []string{"tsvector", "tsquery", "regconfig"} {
+		protoType, err := pgproto.ProtoType(pgproto.TypeRef{Name: name}, pgproto.ProtoOptions{})
+		require.NoError(t, err)
+		require.Equal(t, "string", protoType)
+
+		goType, err := pgproto.GoType(pgproto.TypeRef{Name: name})
+		require.NoError(t, err)
+		require.Equal(t, "string", goType)
+	}
+}
+
+func TestSystemColumnTypes(t *testing.T) {
+	for _, name := range []string{"tid", "xid"} {
+		protoType, err := pgproto.ProtoType(pgproto.TypeRef{Name: name}, pgproto.ProtoOptions{})
+		require.NoError(t, err)
+		require.Equal(t, "string", protoType)
+
+		goType, err := pgproto.GoType(pgproto.TypeRef{Name: name})
+		require.NoError(t, err)
+		require.Equal(t, "string", goType)
+	}
+}
+
+func TestInternalCharType(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT relkind::"char" AS kind_1, code::char AS code_2 FROM pg_class`))
+	require.NoError(t, err)
+
+	outputs := actions[0].(*pgproto.SelectAction).Outputs
+	require.Equal(t, pgproto.TypeRef{Name: "char"}, outputs[0].Type)
+	require.Equal(t, "bpchar", outputs[1].Type.Name)
+
+	protoType, err := pgproto.ProtoType(outputs[0].Type, pgproto.ProtoOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "bytes", protoType)
+
+	goType, err := pgproto.GoType(outputs[0].Type)
+	require.NoError(t, err)
+	require.Equal(t, "byte", goType)
+}
+
+func TestWellKnownTypeOutputs(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1, created_at::timestamptz AS created_2,
+		meta::jsonb AS meta_3, age::interval AS age_4, name::text AS name_5 FROM users`))
+	require.NoError(t, err)
+
+	outputs := pgproto.WellKnownTypeOutputs(actions[0])
+	require.Len(t, outputs, 3)
+	require.Equal(t, "created_2", outputs[0].Name)
+	require.Equal(t, "meta_3", outputs[1].Name)
+	require.Equal(t, "age_4", outputs[2].Name)
+
+	actions, err = pgproto.ParseFullTyped([]byte(`SELECT id::uuid AS id_1 FROM users`))
+	require.NoError(t, err)
+	require.Empty(t, pgproto.WellKnownTypeOutputs(actions[0]))
+}
+
+func TestXMLType(t *testing.T) {
+	protoType, err := pgproto.ProtoType(pgproto.TypeRef{Name: "xml"}, pgproto.ProtoOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "string", protoType)
+
+	goType, err := pgproto.GoType(pgproto.TypeRef{Name: "xml"})
+	require.NoError(t, err)
+	require.Equal(t, "string", goType)
+}
+
+func TestCollectTypes(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`
+SELECT id::uuid AS id_1, age::pg_catalog.int4 AS age_2, n::int4 AS n_3 FROM users WHERE at > @at_1::timestamptz;
+UPDATE users SET name = @name_1::text WHERE id = @id_2::uuid RETURNING tags::text[] AS tags_1;`))
+	require.NoError(t, err)
+
+	catalog := "pg_catalog"
+	require.Equal(t, []pgproto.TypeRef{
+		{Name: "int4"},
+		{Schema: &catalog, Name: "int4"},
+		{Name: "text"},
+		{Name: "timestamptz"},
+		{Name: "uuid"},
+	}, pgproto.CollectTypes(actions))
+
+	require.Empty(t, pgproto.CollectTypes(nil))
+
+	actions, err = pgproto.ParseFullTyped([]byte(`SELECT a::varchar(10) AS a_1, b::varchar(255) AS b_2 FROM t`))
+	require.NoError(t, err)
+
+	catalogVarchar := pgproto.TypeRef{Schema: &catalog, Name: "varchar"}
+	require.Equal(t, []pgproto.TypeRef{catalogVarchar}, pgproto.CollectTypes(actions))
+}
+
+func TestCanonicalTypeNames(t *testing.T) {
+	public := "public"
+	for _, tt := range []struct {
+		cast string
+		exp  pgproto.TypeRef
+	}{
+		{cast: "integer", exp: pgproto.TypeRef{Name: "int4"}},
+		{cast: "int", exp: pgproto.TypeRef{Name: "int4"}},
+		{cast: "int4", exp: pgproto.TypeRef{Name: "int4"}},
+		{cast: "pg_catalog.int4", exp: pgproto.TypeRef{Name: "int4"}},
+		{cast: "bigint", exp: pgproto.TypeRef{Name: "int8"}},
+		{cast: "boolean", exp: pgproto.TypeRef{Name: "bool"}},
+		{cast: "character varying(20)", exp: pgproto.TypeRef{Name: "varchar", TypeMods: []int{20}}},
+		{cast: "double precision", exp: pgproto.TypeRef{Name: "float8"}},
+		{cast: "timestamp with time zone", exp: pgproto.TypeRef{Name: "timestamptz"}},
+		{cast: `pg_catalog."integer"`, exp: pgproto.TypeRef{Name: "int4"}},
+		{cast: `"char"`, exp: pgproto.TypeRef{Name: "char"}},
+		{cast: "public.email", exp: pgproto.TypeRef{Schema: &public, Name: "email"}},
+	} {
+		actions, err := pgproto.ParseFullTyped([]byte(`SELECT x::`+tt.cast+` AS x_1 FROM t WHERE y = @y_1::`+tt.cast),
+			pgproto.WithCanonicalTypeNames())
+		require.NoError(t, err, tt.cast)
+
+		sel := actions[0].(*pgproto.SelectAction)
+		require.Equal(t, tt.exp, sel.Outputs[0].Type, tt.cast)
+		require.Equal(t, tt.exp, sel.Inputs[0].Type, tt.cast)
+	}
+
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT id::integer AS id_1 FROM t`))
+	require.NoError(t, err)
+	require.Equal(t, "pg_catalog.int4", actions[0].(*pgproto.SelectAction).Outputs[0].Type.String())
+}
+
+func TestTypeMods(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`SELECT name::character varying(255) AS name_1,
+		price::numeric(10, 2) AS price_2, bio::text AS bio_3 FROM users WHERE code = @code_1::bpchar(3)`))
+	require.NoError(t, err)
+
+	sel := actions[0].(*pgproto.SelectAction)
+	require.Equal(t, []int{255}, sel.Outputs[0].Type.TypeMods)
+	require.Equal(t, "pg_catalog.varchar", sel.Outputs[0].Type.String())
+	require.Equal(t, []int{10, 2}, sel.Outputs[1].Type.TypeMods)
+	require.Nil(t, sel.Outputs[2].Type.TypeMods)
+	require.Equal(t, []int{3}, sel.Inputs[0].Type.TypeMods)
+}
@@ -0,0 +1,40 @@
+package pgproto
+
+import "fmt"
+
+// Visitor is called with the concrete type of an action, see [Action.Accept]. This allows tooling to handle each
+// kind of action without a type switch.
+type Visitor interface {
+	VisitSelect(action *SelectAction) error
+	VisitInsert(action *InsertAction) error
+	VisitUpdate(action *UpdateAction) error
+	VisitDelete(action *DeleteAction) error
+	VisitMerge(action *MergeAction) error
+}
+
+// Accept calls the visitor's method for selects.
+func (a *SelectAction) Accept(v Visitor) error { return v.VisitSelect(a) }
+
+// Accept calls the visitor's method for inserts.
+func (a *InsertAction) Accept(v Visitor) error { return v.VisitInsert(a) }
+
+// Accept calls the visitor's method for updates.
+func (a *UpdateAction) Accept(v Visitor) error { return v.VisitUpdate(a) }
+
+// Accept calls the visitor's method for deletes.
+func (a *DeleteAction) Accept(v Visitor) error { return v.VisitDelete(a) }
+
+// Accept calls the visitor's method for merges.
+func (a *MergeAction) Accept(v Visitor) error { return v.VisitMerge(a) }
+
+// Walk calls fn for each action in order. It stops at the first error, which is returned prefixed with the action's
+// [MethodNames].
+func Walk(actions []Action, fn func(Action) error) error {
+	for idx, name := range MethodNames(actions) {
+		if err := fn(actions[idx]); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package pgproto_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/crewlinker/pgproto"
+	"github.com/stretchr/testify/require"
+)
+
+// typeCollector collects the types of the outputs of each kind of action.
+type typeCollector struct{ types map[string][]string }
+
+func (c *typeCollector) collect(kind string, outputs []*pgproto.Output) error {
+	for _, output := range outputs {
+		c.types[kind] = append(c.types[kind], output.Type.String())
+	}
+
+	return nil
+}
+
+func (c *typeCollector) VisitSelect(a *pgproto.SelectAction) error {
+	return c.collect("select", a.Outputs)
+}
+
+func (c *typeCollector) VisitInsert(a *pgproto.InsertAction) error {
+	return c.collect("insert", a.Outputs)
+}
+
+func (c *typeCollector) VisitUpdate(a *pgproto.UpdateAction) error {
+	return c.collect("update", a.Outputs)
+}
+
+func (c *typeCollector) VisitDelete(a *pgproto.DeleteAction) error {
+	return c.collect("delete", a.Outputs)
+}
+
+func (c *typeCollector) VisitMerge(a *pgproto.MergeAction) error {
+	return c.collect("merge", a.Outputs)
+}
+
+func TestWalkVisitor(t *testing.T) {
+	actions, err := pgproto.ParseFullTyped([]byte(`
+SELECT id::uuid AS id_1, name::text AS name_2 FROM users;
+INSERT INTO users (id) VALUES (@id_1::uuid) RETURNING created_at::timestamptz AS created_1;
+UPDATE users SET name = @name_1::text RETURNING id::uuid AS id_1;
+DELETE FROM users WHERE id = @id_1::uuid;`))
+	require.NoError(t, err)
+
+	collector := &typeCollector{types: map[string][]string{}}
+	require.NoError(t, pgproto.Walk(actions, func(a pgproto.Action) error { return a.Accept(collector) }))
+	require.Equal(t, map[string][]string{
+		"select": {"uuid", "text"},
+		"insert": {"timestamptz"},
+		"update": {"uuid"},
+	}, collector.types)
+
+	var visited int
+
+	err = pgproto.Walk(actions, func(a pgproto.Action) error {
+		if visited++; visited == 2 {
+			return errors.New("stop")
+		}
+
+		return nil
+	})
+	require.EqualError(t, err, "InsertUsers: stop")
+	require.Equal(t, 2, visited)
+}
@@ -0,0 +1,43 @@
+package pgproto
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// walk calls fn for every message in the tree rooted at msg, depth-first and in field declaration order. Since the
+// parse tree contains nodes both wrapped in a *pgquery.Node and directly (e.g: the relation of an insert), callers
+// should type switch on the concrete messages they are interested in. If fn returns false, the children of that
+// message are not visited.
+func walk(msg proto.Message, fn func(msg proto.Message) bool) {
+	if msg == nil {
+		return
+	}
+
+	walkMessage(msg.ProtoReflect(), fn)
+}
+
+func walkMessage(msg protoreflect.Message, fn func(msg proto.Message) bool) {
+	if !msg.IsValid() || !fn(msg.Interface()) {
+		return
+	}
+
+	fields := msg.Descriptor().Fields()
+	for i := range fields.Len() {
+		field := fields.Get(i)
+		if field.Kind() != protoreflect.MessageKind || !msg.Has(field) {
+			continue
+		}
+
+		if field.IsList() {
+			list := msg.Get(field).List()
+			for j := range list.Len() {
+				walkMessage(list.Get(j).Message(), fn)
+			}
+
+			continue
+		}
+
+		walkMessage(msg.Get(field).Message(), fn)
+	}
+}